@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/paralleltree/mastoshield/rule"
+	"github.com/urfave/cli/v2"
+)
+
+func fingerprintCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "fingerprint",
+		Usage: "Manages media fingerprint lists used by media_fingerprint rules",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "add",
+				Usage:     "Adds fingerprints of the media attached to activities to the list",
+				ArgsUsage: "[activity json file...]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "file",
+						Required: true,
+						Usage:    "Specify the fingerprint list file",
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					paths := ctx.Args().Slice()
+					if len(paths) == 0 {
+						// read an activity from stdin
+						paths = []string{"-"}
+					}
+					for _, path := range paths {
+						body, err := readActivityFile(path, ctx.App.Reader)
+						if err != nil {
+							return fmt.Errorf("read activity %s: %w", path, err)
+						}
+						added, skipped, err := addFingerprints(ctx.String("file"), body)
+						if err != nil {
+							return fmt.Errorf("add fingerprints from %s: %w", path, err)
+						}
+						for _, err := range skipped {
+							fmt.Fprintf(ctx.App.ErrWriter, "%s: skipped %v\n", path, err)
+						}
+						fmt.Fprintf(ctx.App.Writer, "%s: added %d fingerprints\n", path, added)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func readActivityFile(path string, stdin io.Reader) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// addFingerprints appends the fingerprints of the activity which are not in the list yet, and returns the number of them.
+// Malformed fingerprints are not written and returned as skipped.
+func addFingerprints(listPath string, activityBody []byte) (int, []error, error) {
+	activity, err := rule.ParseActivity(activityBody)
+	if err != nil {
+		return 0, nil, fmt.Errorf("parse activity: %w", err)
+	}
+
+	list, err := os.ReadFile(listPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, nil, fmt.Errorf("read file: %w", err)
+	}
+	fingerprints, err := rule.ParseMediaFingerprints(bytes.NewReader(list))
+	if err != nil {
+		return 0, nil, fmt.Errorf("parse fingerprint file: %w", err)
+	}
+	known := map[rule.MediaFingerprint]struct{}{}
+	for _, fingerprint := range fingerprints {
+		known[fingerprint] = struct{}{}
+	}
+
+	lines := []string{}
+	skipped := []error{}
+	for _, fingerprint := range rule.ActivityFingerprints(activity) {
+		if err := fingerprint.Validate(); err != nil {
+			skipped = append(skipped, err)
+			continue
+		}
+		if _, ok := known[fingerprint]; ok {
+			continue
+		}
+		known[fingerprint] = struct{}{}
+		lines = append(lines, fingerprint.String())
+	}
+	added := len(lines)
+	if added == 0 {
+		return 0, skipped, nil
+	}
+	if id := commentText(activity.ID); id != "" {
+		lines = append([]string{"# " + id}, lines...)
+	}
+	if len(list) > 0 && !bytes.HasSuffix(list, []byte("\n")) {
+		lines = append([]string{""}, lines...)
+	}
+
+	f, err := os.OpenFile(listPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, nil, fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		return 0, nil, fmt.Errorf("write file: %w", err)
+	}
+	return added, skipped, nil
+}
+
+// commentText drops control characters so that the text cannot end the comment line.
+func commentText(s string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestAddFingerprints(t *testing.T) {
+	listPath := filepath.Join(t.TempDir(), "fingerprints.txt")
+	// the existing list lacks the trailing newline
+	if err := os.WriteFile(listPath, []byte("blurhash LNJRyVof~qj[ayayj[j[%Mj[WBay"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	activityBody := []byte(`{
+		"id": "https://spam.example/activities/1",
+		"type": "Create",
+		"object": {
+			"type": "Note",
+			"attachment": [
+				{"type": "Document", "url": "https://spam.example/a.png", "blurhash": "LNJRyVof~qj[ayayj[j[%Mj[WBay"},
+				{"type": "Document", "url": "https://spam.example/b.png"}
+			]
+		}
+	}`)
+
+	added, _, err := addFingerprints(listPath, activityBody)
+	if err != nil {
+		t.Fatalf("add fingerprints: %v", err)
+	}
+	if added != 2 {
+		t.Errorf("unexpected added count: want %d, but got %d", 2, added)
+	}
+
+	added, _, err = addFingerprints(listPath, activityBody)
+	if err != nil {
+		t.Fatalf("add fingerprints: %v", err)
+	}
+	if added != 0 {
+		t.Errorf("unexpected added count: want %d, but got %d", 0, added)
+	}
+
+	got, err := os.ReadFile(listPath)
+	if err != nil {
+		t.Fatalf("read file: %v", err)
+	}
+	want := "blurhash LNJRyVof~qj[ayayj[j[%Mj[WBay\n" +
+		"# https://spam.example/activities/1\n" +
+		"url-sha256 " + rule.URLDigest("https://spam.example/a.png") + "\n" +
+		"url-sha256 " + rule.URLDigest("https://spam.example/b.png") + "\n"
+	if want != string(got) {
+		t.Errorf("unexpected file: want %q, but got %q", want, string(got))
+	}
+}
+
+func TestAddFingerprints_InvalidBlurhash(t *testing.T) {
+	listPath := filepath.Join(t.TempDir(), "fingerprints.txt")
+	activityBody := []byte(`{
+		"type": "Create",
+		"object": {
+			"type": "Note",
+			"attachment": [
+				{"type": "Document", "url": "https://spam.example/a.png", "blurhash": "bad hash!"}
+			]
+		}
+	}`)
+
+	for i := 0; i < 2; i++ {
+		added, skipped, err := addFingerprints(listPath, activityBody)
+		if err != nil {
+			t.Fatalf("add fingerprints: %v", err)
+		}
+		if wantAdded := 1 - i; added != wantAdded {
+			t.Errorf("unexpected added count: want %d, but got %d", wantAdded, added)
+		}
+		if len(skipped) != 1 {
+			t.Errorf("unexpected skipped fingerprints: want 1, but got %v", skipped)
+		}
+	}
+
+	got, err := os.ReadFile(listPath)
+	if err != nil {
+		t.Fatalf("read file: %v", err)
+	}
+	want := "url-sha256 " + rule.URLDigest("https://spam.example/a.png") + "\n"
+	if want != string(got) {
+		t.Errorf("unexpected file: want %q, but got %q", want, string(got))
+	}
+}
+
+func TestAddFingerprints_ControlCharactersInID(t *testing.T) {
+	listPath := filepath.Join(t.TempDir(), "fingerprints.txt")
+	activityBody := []byte(`{
+		"id": "https://spam.example/1\nblurhash x\r",
+		"type": "Create",
+		"object": {
+			"type": "Note",
+			"attachment": [
+				{"type": "Document", "url": "https://spam.example/a.png"}
+			]
+		}
+	}`)
+
+	if _, _, err := addFingerprints(listPath, activityBody); err != nil {
+		t.Fatalf("add fingerprints: %v", err)
+	}
+
+	got, err := os.ReadFile(listPath)
+	if err != nil {
+		t.Fatalf("read file: %v", err)
+	}
+	want := "# https://spam.example/1blurhash x\n" +
+		"url-sha256 " + rule.URLDigest("https://spam.example/a.png") + "\n"
+	if want != string(got) {
+		t.Errorf("unexpected file: want %q, but got %q", want, string(got))
+	}
+	if _, err := rule.ParseMediaFingerprints(bytes.NewReader(got)); err != nil {
+		t.Errorf("parse fingerprints: %v", err)
+	}
+}
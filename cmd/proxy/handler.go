@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/paralleltree/mastoshield/lib"
+	"github.com/paralleltree/mastoshield/rule"
+	"github.com/rs/xid"
+)
+
+type HandlerConfig struct {
+	DenyResponseCode int
+	// DryRun forwards denied requests to upstream while reporting them as denied.
+	DryRun           bool
+	ClientIPResolver *lib.ClientIPResolver
+}
+
+type HandlerHooks struct {
+	OnProcessing func(reqID string, r *http.Request)
+	// OnAllowed and OnDenied receive the matched ruleset, or nil when the default action is applied.
+	// OnDenied is also called for requests exceeding the rate limit.
+	OnAllowed func(reqID string, r *http.Request, ruleset *rule.RuleSet)
+	OnDenied  func(reqID string, r *http.Request, ruleset *rule.RuleSet)
+	// OnDryRunDenied is called instead of OnDenied when the request is forwarded in dry-run mode.
+	OnDryRunDenied func(reqID string, r *http.Request, ruleset *rule.RuleSet)
+	// OnLogged is called when a ruleset with the log action matched.
+	OnLogged func(reqID string, r *http.Request, ruleset *rule.RuleSet)
+	OnError  func(reqID string, err error)
+	// OnMatcherTested is called every time a matcher of the ruleset is evaluated.
+	OnMatcherTested func(reqID string, ruleset *rule.RuleSet, matcherIndex int, matched bool, err error)
+	// OnRuleSetMatched is called when all matchers of the ruleset matched.
+	OnRuleSetMatched func(reqID string, ruleset *rule.RuleSet)
+}
+
+func Handler(
+	upstream http.Handler, conf HandlerConfig, rulesets func() []rule.RuleSet, hooks HandlerHooks,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reqID := xid.New().String()
+
+		if hooks.OnProcessing != nil {
+			hooks.OnProcessing(reqID, r)
+		}
+
+		testRequest := func(r *rule.ProxyRequest, ruleset *rule.RuleSet) (bool, error) {
+			for matcherIndex, matcher := range ruleset.Matchers {
+				matched, err := matcher.Test(r)
+				if hooks.OnMatcherTested != nil {
+					hooks.OnMatcherTested(reqID, ruleset, matcherIndex, matched, err)
+				}
+				if err != nil {
+					return false, fmt.Errorf("test request: %w", err)
+				}
+				if !matched {
+					return false, nil
+				}
+			}
+			return true, nil
+		}
+
+		allowAction := func(w http.ResponseWriter, r *http.Request, ruleset *rule.RuleSet) {
+			if hooks.OnAllowed != nil {
+				defer hooks.OnAllowed(reqID, r, ruleset)
+			}
+			upstream.ServeHTTP(w, r)
+		}
+		denyAction := func(w http.ResponseWriter, r *http.Request, ruleset *rule.RuleSet) {
+			if conf.DryRun {
+				if hooks.OnDryRunDenied != nil {
+					defer hooks.OnDryRunDenied(reqID, r, ruleset)
+				}
+				upstream.ServeHTTP(w, r)
+				return
+			}
+			if hooks.OnDenied != nil {
+				defer hooks.OnDenied(reqID, r, ruleset)
+			}
+			if ruleset.Response != nil {
+				for key, values := range ruleset.Response.Header {
+					w.Header()[key] = values
+				}
+				statusCode := ruleset.Response.StatusCode
+				if statusCode == 0 {
+					statusCode = conf.DenyResponseCode
+				}
+				w.WriteHeader(statusCode)
+				w.Write(ruleset.Response.Body)
+				return
+			}
+			w.WriteHeader(conf.DenyResponseCode)
+			w.Write([]byte{})
+		}
+		rateLimitAction := func(w http.ResponseWriter, r *http.Request, ruleset *rule.RuleSet, retryAfter time.Duration) {
+			if conf.DryRun {
+				if hooks.OnDryRunDenied != nil {
+					defer hooks.OnDryRunDenied(reqID, r, ruleset)
+				}
+				upstream.ServeHTTP(w, r)
+				return
+			}
+			if hooks.OnDenied != nil {
+				defer hooks.OnDenied(reqID, r, ruleset)
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte{})
+		}
+		logAction := func(r *http.Request, ruleset *rule.RuleSet) {
+			if hooks.OnLogged != nil {
+				hooks.OnLogged(reqID, r, ruleset)
+			}
+		}
+		errAction := func(w http.ResponseWriter, r *http.Request, err error) {
+			if hooks.OnError != nil {
+				defer hooks.OnError(reqID, err)
+			}
+			allowAction(w, r, nil)
+		}
+
+		proxyRequest := rule.NewProxyRequest(r)
+		proxyRequest.ClientIPResolver = conf.ClientIPResolver
+		for _, ruleset := range rulesets() {
+			matched, err := testRequest(proxyRequest, &ruleset)
+			if err != nil {
+				errAction(w, r, err)
+				return
+			}
+			if matched {
+				if hooks.OnRuleSetMatched != nil {
+					hooks.OnRuleSetMatched(reqID, &ruleset)
+				}
+				switch ruleset.Action {
+				case rule.ACTION_LOG:
+					logAction(r, &ruleset)
+					continue
+				case rule.ACTION_RATE_LIMIT:
+					allowed, retryAfter, err := ruleset.RateLimiter.Take(proxyRequest)
+					if err != nil {
+						errAction(w, r, err)
+						return
+					}
+					if allowed {
+						continue
+					}
+					rateLimitAction(w, r, &ruleset, retryAfter)
+				case rule.ACTION_ALLOW:
+					allowAction(w, r, &ruleset)
+				case rule.ACTION_DENY:
+					denyAction(w, r, &ruleset)
+				default:
+					errAction(w, r, fmt.Errorf("unexpected action: %v", ruleset.Action))
+				}
+				return
+			}
+		}
+
+		// default action(allow)
+		allowAction(w, r, nil)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestHandler_Actions(t *testing.T) {
+	botMatcher, err := rule.NewUserAgentMatcher("bot")
+	if err != nil {
+		t.Fatalf("create matcher: %v", err)
+	}
+	curlMatcher, err := rule.NewUserAgentMatcher("curl")
+	if err != nil {
+		t.Fatalf("create matcher: %v", err)
+	}
+
+	cases := []struct {
+		name         string
+		conf         HandlerConfig
+		rulesets     []rule.RuleSet
+		userAgent    string
+		wantStatus   int
+		wantAction   string
+		wantRuleName string
+		wantLogged   []string
+	}{
+		{
+			name: "deny",
+			conf: HandlerConfig{DenyResponseCode: http.StatusNotFound},
+			rulesets: []rule.RuleSet{
+				{Name: "deny-bot", Action: rule.ACTION_DENY, Matchers: []rule.RuleMatcher{botMatcher}},
+			},
+			userAgent:    "bot",
+			wantStatus:   http.StatusNotFound,
+			wantAction:   "deny",
+			wantRuleName: "deny-bot",
+		},
+		{
+			name: "default action",
+			conf: HandlerConfig{DenyResponseCode: http.StatusNotFound},
+			rulesets: []rule.RuleSet{
+				{Name: "deny-bot", Action: rule.ACTION_DENY, Matchers: []rule.RuleMatcher{botMatcher}},
+			},
+			userAgent:    "browser",
+			wantStatus:   http.StatusOK,
+			wantAction:   "allow",
+			wantRuleName: "-",
+		},
+		{
+			name: "log action continues evaluation",
+			conf: HandlerConfig{DenyResponseCode: http.StatusNotFound},
+			rulesets: []rule.RuleSet{
+				{Name: "log-bot", Action: rule.ACTION_LOG, Matchers: []rule.RuleMatcher{botMatcher}},
+				{Name: "log-bot-again", Action: rule.ACTION_LOG, Matchers: []rule.RuleMatcher{botMatcher}},
+				{Name: "log-curl", Action: rule.ACTION_LOG, Matchers: []rule.RuleMatcher{curlMatcher}},
+				{Name: "deny-bot", Action: rule.ACTION_DENY, Matchers: []rule.RuleMatcher{botMatcher}},
+			},
+			userAgent:    "bot",
+			wantStatus:   http.StatusNotFound,
+			wantAction:   "deny",
+			wantRuleName: "deny-bot",
+			wantLogged:   []string{"log-bot", "log-bot-again"},
+		},
+		{
+			name: "dry run forwards denied request",
+			conf: HandlerConfig{DenyResponseCode: http.StatusNotFound, DryRun: true},
+			rulesets: []rule.RuleSet{
+				{Name: "deny-bot", Action: rule.ACTION_DENY, Matchers: []rule.RuleMatcher{botMatcher}},
+			},
+			userAgent:    "bot",
+			wantStatus:   http.StatusOK,
+			wantAction:   "dry_run_deny",
+			wantRuleName: "deny-bot",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAction, gotRuleName := "", ""
+			gotLogged := []string{}
+			report := func(action string) func(string, *http.Request, *rule.RuleSet) {
+				return func(reqID string, r *http.Request, ruleset *rule.RuleSet) {
+					gotAction, gotRuleName = action, "-"
+					if ruleset != nil {
+						gotRuleName = ruleset.Name
+					}
+				}
+			}
+			hooks := HandlerHooks{
+				OnAllowed:      report("allow"),
+				OnDenied:       report("deny"),
+				OnDryRunDenied: report("dry_run_deny"),
+				OnLogged: func(reqID string, r *http.Request, ruleset *rule.RuleSet) {
+					gotLogged = append(gotLogged, ruleset.Name)
+				},
+			}
+			upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			handler := Handler(upstream, tt.conf, func() []rule.RuleSet { return tt.rulesets }, hooks)
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+			w := httptest.NewRecorder()
+			handler(w, req)
+
+			if tt.wantStatus != w.Code {
+				t.Errorf("unexpected status: want %d, but got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantAction != gotAction {
+				t.Errorf("unexpected action: want %s, but got %s", tt.wantAction, gotAction)
+			}
+			if tt.wantRuleName != gotRuleName {
+				t.Errorf("unexpected matched rule: want %s, but got %s", tt.wantRuleName, gotRuleName)
+			}
+			if len(tt.wantLogged) != len(gotLogged) {
+				t.Fatalf("unexpected logged rules: want %v, but got %v", tt.wantLogged, gotLogged)
+			}
+			for i := range tt.wantLogged {
+				if tt.wantLogged[i] != gotLogged[i] {
+					t.Errorf("unexpected logged rules: want %v, but got %v", tt.wantLogged, gotLogged)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_DenyResponse(t *testing.T) {
+	botMatcher, err := rule.NewUserAgentMatcher("bot")
+	if err != nil {
+		t.Fatalf("create matcher: %v", err)
+	}
+	rulesets := []rule.RuleSet{
+		{
+			Name:     "discard-bot",
+			Action:   rule.ACTION_DENY,
+			Matchers: []rule.RuleMatcher{botMatcher},
+			Response: &rule.DenyResponse{
+				StatusCode: http.StatusAccepted,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       []byte("{}"),
+			},
+		},
+	}
+	upstreamCalled := false
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalled = true
+	})
+	handler := Handler(upstream, HandlerConfig{DenyResponseCode: http.StatusNotFound}, func() []rule.RuleSet { return rulesets }, HandlerHooks{})
+
+	req := httptest.NewRequest("POST", "/inbox", nil)
+	req.Header.Set("User-Agent", "bot")
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if upstreamCalled {
+		t.Errorf("denied request is forwarded to upstream")
+	}
+	if w.Code != http.StatusAccepted {
+		t.Errorf("unexpected status: want %d, but got %d", http.StatusAccepted, w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("unexpected content type: %s", got)
+	}
+	if got := w.Body.String(); got != "{}" {
+		t.Errorf("unexpected body: %s", got)
+	}
+}
+
+func TestHandler_DenyResponseDefaultStatus(t *testing.T) {
+	botMatcher, err := rule.NewUserAgentMatcher("bot")
+	if err != nil {
+		t.Fatalf("create matcher: %v", err)
+	}
+	rulesets := []rule.RuleSet{
+		{
+			Name:     "deny-bot",
+			Action:   rule.ACTION_DENY,
+			Matchers: []rule.RuleMatcher{botMatcher},
+			Response: &rule.DenyResponse{
+				Header: http.Header{"Content-Type": []string{"text/plain"}},
+				Body:   []byte("forbidden"),
+			},
+		},
+	}
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := Handler(upstream, HandlerConfig{DenyResponseCode: http.StatusForbidden}, func() []rule.RuleSet { return rulesets }, HandlerHooks{})
+
+	req := httptest.NewRequest("POST", "/inbox", nil)
+	req.Header.Set("User-Agent", "bot")
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("unexpected status: want %d, but got %d", http.StatusForbidden, w.Code)
+	}
+	if got := w.Body.String(); got != "forbidden" {
+		t.Errorf("unexpected body: %s", got)
+	}
+}
+
+func TestHandler_RateLimit(t *testing.T) {
+	botMatcher, err := rule.NewUserAgentMatcher("bot")
+	if err != nil {
+		t.Fatalf("create matcher: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter, err := rule.NewRateLimiter(rule.RateLimitConfig{
+		Key:     rule.RATE_LIMIT_KEY_REMOTE_IP,
+		Rate:    1,
+		Per:     90 * time.Second,
+		Burst:   1,
+		MaxKeys: 10,
+	}, func() time.Time { return now })
+	if err != nil {
+		t.Fatalf("create rate limiter: %v", err)
+	}
+	rulesets := []rule.RuleSet{
+		{Name: "limit-bot", Action: rule.ACTION_RATE_LIMIT, Matchers: []rule.RuleMatcher{botMatcher}, RateLimiter: limiter},
+		{Name: "log-bot", Action: rule.ACTION_LOG, Matchers: []rule.RuleMatcher{botMatcher}},
+	}
+
+	gotActions := []string{}
+	report := func(action string) func(string, *http.Request, *rule.RuleSet) {
+		return func(reqID string, r *http.Request, ruleset *rule.RuleSet) {
+			gotActions = append(gotActions, action)
+		}
+	}
+	hooks := HandlerHooks{
+		OnAllowed: report("allow"),
+		OnDenied:  report("deny"),
+		OnLogged:  report("log"),
+	}
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Handler(upstream, HandlerConfig{DenyResponseCode: http.StatusNotFound}, func() []rule.RuleSet { return rulesets }, hooks)
+
+	cases := []struct {
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{wantStatus: http.StatusOK},
+		{wantStatus: http.StatusTooManyRequests, wantRetryAfter: "90"},
+	}
+	for _, tt := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("User-Agent", "bot")
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if tt.wantStatus != w.Code {
+			t.Errorf("unexpected status: want %d, but got %d", tt.wantStatus, w.Code)
+		}
+		if got := w.Header().Get("Retry-After"); tt.wantRetryAfter != got {
+			t.Errorf("unexpected retry after: want %s, but got %s", tt.wantRetryAfter, got)
+		}
+	}
+
+	wantActions := []string{"log", "allow", "deny"}
+	if len(wantActions) != len(gotActions) {
+		t.Fatalf("unexpected actions: want %v, but got %v", wantActions, gotActions)
+	}
+	for i := range wantActions {
+		if wantActions[i] != gotActions[i] {
+			t.Errorf("unexpected actions: want %v, but got %v", wantActions, gotActions)
+		}
+	}
+}
@@ -18,7 +18,6 @@ import (
 	"github.com/paralleltree/mastoshield/config"
 	"github.com/paralleltree/mastoshield/lib"
 	"github.com/paralleltree/mastoshield/rule"
-	"github.com/rs/xid"
 	"github.com/urfave/cli/v2"
 )
 
@@ -30,22 +29,32 @@ func main() {
 		Name: "mastoshield",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
-				Name:     "rule-file",
-				Required: true,
-				Usage:    "Specify the yaml file including rules",
+				// required unless a subcommand is given, so it is checked in Action
+				Name:  "rule-file",
+				Usage: "Specify the yaml file including rules",
 			},
 			&cli.BoolFlag{
 				Name:  "test-rule",
 				Usage: "Validates given rule file",
 			},
+			&cli.DurationFlag{
+				Name:  "rule-watch-interval",
+				Usage: "Reloads the rule file when it is modified, checking at the given interval (0 disables)",
+			},
+		},
+		Commands: []*cli.Command{
+			fingerprintCommand(),
 		},
 		Action: func(ctx *cli.Context) error {
 			ruleFilePath := ctx.String("rule-file")
+			if ruleFilePath == "" {
+				return fmt.Errorf("required flag \"rule-file\" not set")
+			}
 			if ctx.Bool("test-rule") {
-				_, err := loadAccessControlConfig(ruleFilePath)
+				_, err := loadAccessControlConfig(config.NewRuleLoader(), ruleFilePath)
 				return err
 			}
-			return run(ctx.Context, ruleFilePath)
+			return run(ctx.Context, ruleFilePath, ctx.Duration("rule-watch-interval"))
 		},
 	}
 	if err := app.RunContext(ctx, os.Args); err != nil {
@@ -54,42 +63,115 @@ func main() {
 	}
 }
 
-func run(ctx context.Context, ruleFilePath string) error {
+func run(ctx context.Context, ruleFilePath string, ruleWatchInterval time.Duration) error {
 	conf, err := config.LoadProxyConfig()
 	if err != nil {
 		return fmt.Errorf("load proxy config: %w", err)
 	}
-	rulesets, err := loadAccessControlConfig(ruleFilePath)
+	loader := config.NewRuleLoader()
+	rulesets, err := loadAccessControlConfig(loader, ruleFilePath)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
 	}
-	if err := start(ctx, conf, rulesets); err != nil {
+	holder := newRuleSetHolder(rulesets)
+	reload := func(trigger string) func() {
+		return func() { reloadRuleSets(holder, loader, ruleFilePath, trigger) }
+	}
+	reloadSignal, stopReloadSignal := notifyReloadSignal()
+	defer stopReloadSignal()
+	go watchReloadSignal(ctx, reloadSignal, reload("signal"))
+	if ruleWatchInterval > 0 {
+		go func() {
+			if err := watchFileChange(ctx, ruleFilePath, ruleWatchInterval, reload("file")); err != nil {
+				ltsvlog.Logger.Err(errstack.WithLV(fmt.Errorf("watch rule file: %w", err)))
+			}
+		}()
+	}
+
+	if err := start(ctx, conf, holder.Load); err != nil {
 		return fmt.Errorf("running server: %w", err)
 	}
 	return nil
 }
 
-func start(ctx context.Context, conf *config.ProxyConfig, rulesets []rule.RuleSet) error {
-	onAllowed := func(xid string, r *http.Request) {
-		reportRequest(xid, r, "allow")
+func start(ctx context.Context, conf *config.ProxyConfig, rulesets func() []rule.RuleSet) error {
+	clientIPResolver, err := lib.NewClientIPResolver(conf.TrustedProxies, conf.ForwardedHeader, conf.IgnoreForwardedHeaders)
+	if err != nil {
+		return fmt.Errorf("create client ip resolver: %w", err)
 	}
-	onDenied := func(xid string, r *http.Request) {
-		reportRequest(xid, r, "deny")
+	report := func(xid string, r *http.Request, action string, ruleset *rule.RuleSet) {
+		reportRequest(xid, r, clientIPResolver, action, ruleset)
+	}
+
+	var metrics *proxyMetrics
+	if conf.MetricsPort > 0 {
+		metrics = newProxyMetrics()
+	}
+
+	hooks := HandlerHooks{
+		OnProcessing: func(xid string, r *http.Request) {
+			if metrics != nil {
+				metrics.ObserveRequest(r)
+			}
+		},
+		OnAllowed: func(xid string, r *http.Request, ruleset *rule.RuleSet) {
+			report(xid, r, "allow", ruleset)
+			if metrics != nil {
+				metrics.CountAction("allow")
+			}
+		},
+		OnDenied: func(xid string, r *http.Request, ruleset *rule.RuleSet) {
+			action := denyActionName(ruleset)
+			report(xid, r, action, ruleset)
+			if metrics != nil {
+				metrics.CountAction(action)
+			}
+		},
+		OnDryRunDenied: func(xid string, r *http.Request, ruleset *rule.RuleSet) {
+			action := "dry_run_" + denyActionName(ruleset)
+			report(xid, r, action, ruleset)
+			if metrics != nil {
+				metrics.CountAction(action)
+			}
+		},
+		OnLogged: func(xid string, r *http.Request, ruleset *rule.RuleSet) {
+			reportRuleMatch(xid, r, "log", ruleset)
+		},
+		OnError: func(xid string, err error) {
+			ltsvlog.Logger.Err(errstack.WithLV(err).String("xid", xid))
+			if metrics != nil {
+				metrics.CountError()
+			}
+		},
 	}
-	onError := func(xid string, err error) {
-		ltsvlog.Logger.Err(errstack.WithLV(err).String("xid", xid))
+	if metrics != nil {
+		hooks.OnMatcherTested = func(xid string, ruleset *rule.RuleSet, matcherIndex int, matched bool, err error) {
+			metrics.CountMatcherResult(ruleset, matcherIndex, matched, err)
+		}
+		hooks.OnRuleSetMatched = func(xid string, ruleset *rule.RuleSet) {
+			metrics.CountRuleSetMatch(ruleset)
+		}
 	}
+
 	upstreamUrl, err := url.Parse(conf.UpstreamEndpoint)
 	if err != nil {
 		return fmt.Errorf("parse upstream url: %w", err)
 	}
-	proxy := httputil.NewSingleHostReverseProxy(upstreamUrl)
+	var upstream http.Handler = httputil.NewSingleHostReverseProxy(upstreamUrl)
+	if metrics != nil {
+		upstream = metrics.InstrumentUpstream(upstream)
+	}
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", Handler(proxy, conf.DenyResponseCode, rulesets, nil, onAllowed, onDenied, onError))
+	handlerConfig := HandlerConfig{
+		DenyResponseCode: conf.DenyResponseCode,
+		DryRun:           conf.DryRun,
+		ClientIPResolver: clientIPResolver,
+	}
+	mux.HandleFunc("/", Handler(upstream, handlerConfig, rulesets, hooks))
 	addr := fmt.Sprintf(":%d", conf.ListenPort)
 	server := &http.Server{Addr: addr, Handler: mux}
 
-	ltsvlog.Logger.Info().String("event", "start").Int("port", conf.ListenPort).String("upstream", conf.UpstreamEndpoint).Log()
+	ltsvlog.Logger.Info().String("event", "start").Int("port", conf.ListenPort).String("upstream", conf.UpstreamEndpoint).Bool("dry_run", conf.DryRun).Log()
 
 	go func(ctx context.Context) {
 		<-ctx.Done()
@@ -100,6 +182,28 @@ func start(ctx context.Context, conf *config.ProxyConfig, rulesets []rule.RuleSe
 		}
 	}(ctx)
 
+	if metrics != nil {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", metrics.Handler())
+		metricsServer := &http.Server{Addr: fmt.Sprintf(":%d", conf.MetricsPort), Handler: metricsMux}
+
+		ltsvlog.Logger.Info().String("event", "startMetrics").Int("port", conf.MetricsPort).Log()
+
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				ltsvlog.Logger.Err(errstack.WithLV(fmt.Errorf("listen and serve metrics: %w", err)))
+			}
+		}()
+		go func(ctx context.Context) {
+			<-ctx.Done()
+			timeout, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(conf.ExitTimeoutSeconds))
+			defer cancel()
+			if err := metricsServer.Shutdown(timeout); err != nil {
+				log.Fatalf("shutdown metrics server: %v", err)
+			}
+		}(ctx)
+	}
+
 	if err := server.ListenAndServe(); err != nil {
 		if !errors.Is(err, http.ErrServerClosed) {
 			return fmt.Errorf("listen and serve: %w", err)
@@ -109,15 +213,20 @@ func start(ctx context.Context, conf *config.ProxyConfig, rulesets []rule.RuleSe
 	return nil
 }
 
-func reportRequest(xid string, r *http.Request, action string) {
-	remote, err := lib.ResolveClientIP(r)
+func reportRequest(xid string, r *http.Request, resolver *lib.ClientIPResolver, action string, ruleset *rule.RuleSet) {
+	remote, err := resolver.Resolve(r)
 	if err != nil {
 		remote = "-"
 	}
+	matchedRule := "-"
+	if ruleset != nil {
+		matchedRule = ruleset.Name
+	}
 	ltsvlog.Logger.Info().
 		String("event", "requestHandled").
 		String("xid", xid).
 		String("action", action).
+		String("matched_rule", matchedRule).
 		String("method", r.Method).
 		String("path", r.URL.Path).
 		String("url", r.URL.String()).
@@ -126,82 +235,31 @@ func reportRequest(xid string, r *http.Request, action string) {
 		Log()
 }
 
-func Handler(
-	upstream http.Handler, denyResponseCode int, rulesets []rule.RuleSet,
-	onProcessing func(string, *http.Request), onAllowed func(string, *http.Request), onDenied func(string, *http.Request),
-	onError func(string, error),
-) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		reqID := xid.New().String()
-
-		if onProcessing != nil {
-			onProcessing(reqID, r)
-		}
-
-		testRequest := func(r *rule.ProxyRequest, ruleset rule.RuleSet) (bool, error) {
-			for _, matcher := range ruleset.Matchers {
-				matched, err := matcher.Test(r)
-				if err != nil {
-					return false, fmt.Errorf("test request: %w", err)
-				}
-				if !matched {
-					return false, nil
-				}
-			}
-			return true, nil
-		}
-
-		allowAction := func(w http.ResponseWriter, r *http.Request) {
-			if onAllowed != nil {
-				defer onAllowed(reqID, r)
-			}
-			upstream.ServeHTTP(w, r)
-		}
-		denyAction := func(w http.ResponseWriter, r *http.Request) {
-			if onDenied != nil {
-				defer onDenied(reqID, r)
-			}
-			w.WriteHeader(denyResponseCode)
-			w.Write([]byte{})
-		}
-		errAction := func(w http.ResponseWriter, r *http.Request, err error) {
-			if onError != nil {
-				defer onError(reqID, err)
-			}
-			allowAction(w, r)
-		}
-
-		for _, ruleset := range rulesets {
-			matched, err := testRequest(rule.NewProxyRequest(r), ruleset)
-			if err != nil {
-				errAction(w, r, err)
-				return
-			}
-			if matched {
-				switch ruleset.Action {
-				case rule.ACTION_ALLOW:
-					allowAction(w, r)
-				case rule.ACTION_DENY:
-					denyAction(w, r)
-				default:
-					errAction(w, r, fmt.Errorf("unexpected action: %v", ruleset.Action))
-				}
-				return
-			}
-		}
-
-		// default action(allow)
-		allowAction(w, r)
+func denyActionName(ruleset *rule.RuleSet) string {
+	if ruleset != nil && ruleset.Action == rule.ACTION_RATE_LIMIT {
+		return "rate_limit"
 	}
+	return "deny"
+}
+
+func reportRuleMatch(xid string, r *http.Request, action string, ruleset *rule.RuleSet) {
+	ltsvlog.Logger.Info().
+		String("event", "ruleMatched").
+		String("xid", xid).
+		String("action", action).
+		String("matched_rule", ruleset.Name).
+		String("method", r.Method).
+		String("path", r.URL.Path).
+		Log()
 }
 
-func loadAccessControlConfig(path string) ([]rule.RuleSet, error) {
+func loadAccessControlConfig(loader *config.RuleLoader, path string) ([]rule.RuleSet, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("open file: %w", err)
 	}
 	defer f.Close()
-	conf, err := config.LoadAccessControlConfig(f)
+	conf, err := loader.Load(f)
 	if err != nil {
 		return nil, fmt.Errorf("load config: %w", err)
 	}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+const benchmarkActivityBody = `{
+	"id": "https://example.com/users/alice/statuses/1/activity",
+	"type": "Create",
+	"actor": "https://example.com/users/alice",
+	"to": ["https://www.w3.org/ns/activitystreams#Public"],
+	"object": {
+		"id": "https://example.com/users/alice/statuses/1",
+		"type": "Note",
+		"content": "<p>hello, world</p>",
+		"tag": [
+			{"type": "Mention", "href": "https://example.net/users/bob", "name": "@bob@example.net"}
+		]
+	}
+}`
+
+func BenchmarkHandler(b *testing.B) {
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	mentionThreshold := 5
+	for _, rulesetCount := range []int{1, 10, 100} {
+		rulesets := make([]rule.RuleSet, 0, rulesetCount)
+		for i := 0; i < rulesetCount; i++ {
+			noteContentMatcher, err := rule.NewNoteContentMatcher(fmt.Sprintf("blocked text %d", i))
+			if err != nil {
+				b.Fatalf("create matcher: %v", err)
+			}
+			mentionCountMatcher, err := rule.NewMentionCountMatcher(rule.CountCondition{GreaterThan: &mentionThreshold})
+			if err != nil {
+				b.Fatalf("create matcher: %v", err)
+			}
+			rulesets = append(rulesets, rule.RuleSet{
+				Action:   rule.ACTION_DENY,
+				Matchers: []rule.RuleMatcher{mentionCountMatcher, noteContentMatcher},
+			})
+		}
+		handler := Handler(upstream, HandlerConfig{DenyResponseCode: http.StatusNotFound}, func() []rule.RuleSet { return rulesets }, HandlerHooks{})
+
+		b.Run(fmt.Sprintf("rulesets=%d", rulesetCount), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				req := httptest.NewRequest("POST", "/inbox", bytes.NewBufferString(benchmarkActivityBody))
+				handler(httptest.NewRecorder(), req)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/paralleltree/mastoshield/rule"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type proxyMetrics struct {
+	registry            *prometheus.Registry
+	requestsTotal       *prometheus.CounterVec
+	rulesetMatchesTotal *prometheus.CounterVec
+	matcherMatchesTotal *prometheus.CounterVec
+	matcherErrorsTotal  *prometheus.CounterVec
+	errorsTotal         prometheus.Counter
+	upstreamDuration    prometheus.Histogram
+	requestBodySize     prometheus.Histogram
+}
+
+func newProxyMetrics() *proxyMetrics {
+	m := &proxyMetrics{
+		registry: prometheus.NewRegistry(),
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mastoshield",
+			Name:      "requests_total",
+			Help:      "Total number of handled requests by action.",
+		}, []string{"action"}),
+		rulesetMatchesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mastoshield",
+			Name:      "ruleset_matches_total",
+			Help:      "Total number of requests matched by each ruleset.",
+		}, []string{"ruleset"}),
+		matcherMatchesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mastoshield",
+			Name:      "matcher_matches_total",
+			Help:      "Total number of requests matched by each matcher of rulesets.",
+		}, []string{"ruleset", "matcher"}),
+		matcherErrorsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mastoshield",
+			Name:      "matcher_errors_total",
+			Help:      "Total number of errors returned by each matcher of rulesets.",
+		}, []string{"ruleset", "matcher"}),
+		errorsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "mastoshield",
+			Name:      "errors_total",
+			Help:      "Total number of requests forwarded to upstream because of errors.",
+		}),
+		upstreamDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "mastoshield",
+			Name:      "upstream_duration_seconds",
+			Help:      "Latency of requests forwarded to upstream.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		requestBodySize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "mastoshield",
+			Name:      "request_body_bytes",
+			Help:      "Size of request bodies.",
+			Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requestsTotal,
+		m.rulesetMatchesTotal,
+		m.matcherMatchesTotal,
+		m.matcherErrorsTotal,
+		m.errorsTotal,
+		m.upstreamDuration,
+		m.requestBodySize,
+	)
+	return m
+}
+
+func (m *proxyMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// InstrumentUpstream measures the latency of the upstream handler.
+func (m *proxyMetrics) InstrumentUpstream(upstream http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		defer func() {
+			m.upstreamDuration.Observe(time.Since(start).Seconds())
+		}()
+		upstream.ServeHTTP(w, r)
+	})
+}
+
+func (m *proxyMetrics) ObserveRequest(r *http.Request) {
+	if r.ContentLength >= 0 {
+		m.requestBodySize.Observe(float64(r.ContentLength))
+	}
+}
+
+func (m *proxyMetrics) CountAction(action string) {
+	m.requestsTotal.WithLabelValues(action).Inc()
+}
+
+func (m *proxyMetrics) CountError() {
+	m.errorsTotal.Inc()
+}
+
+func (m *proxyMetrics) CountMatcherResult(ruleset *rule.RuleSet, matcherIndex int, matched bool, err error) {
+	matcher := strconv.Itoa(matcherIndex)
+	if err != nil {
+		m.matcherErrorsTotal.WithLabelValues(ruleset.Name, matcher).Inc()
+		return
+	}
+	if matched {
+		m.matcherMatchesTotal.WithLabelValues(ruleset.Name, matcher).Inc()
+	}
+}
+
+func (m *proxyMetrics) CountRuleSetMatch(ruleset *rule.RuleSet) {
+	m.rulesetMatchesTotal.WithLabelValues(ruleset.Name).Inc()
+}
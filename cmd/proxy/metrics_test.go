@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type testMatcher func(req *rule.ProxyRequest) (bool, error)
+
+func (m testMatcher) Test(req *rule.ProxyRequest) (bool, error) {
+	return m(req)
+}
+
+func TestProxyMetrics(t *testing.T) {
+	userAgentMatcher, err := rule.NewUserAgentMatcher("bot")
+	if err != nil {
+		t.Fatalf("create matcher: %v", err)
+	}
+	errorMatcher := testMatcher(func(req *rule.ProxyRequest) (bool, error) {
+		if req.Request.Header.Get("User-Agent") == "broken" {
+			return false, fmt.Errorf("test error")
+		}
+		return false, nil
+	})
+	rulesets := []rule.RuleSet{
+		{Name: "deny-bot", Action: rule.ACTION_DENY, Matchers: []rule.RuleMatcher{userAgentMatcher}},
+		{Name: "#1", Action: rule.ACTION_DENY, Matchers: []rule.RuleMatcher{errorMatcher}},
+	}
+
+	metrics := newProxyMetrics()
+	hooks := HandlerHooks{
+		OnProcessing: func(reqID string, r *http.Request) { metrics.ObserveRequest(r) },
+		OnAllowed:    func(reqID string, r *http.Request, ruleset *rule.RuleSet) { metrics.CountAction("allow") },
+		OnDenied:     func(reqID string, r *http.Request, ruleset *rule.RuleSet) { metrics.CountAction("deny") },
+		OnError:      func(reqID string, err error) { metrics.CountError() },
+		OnMatcherTested: func(reqID string, ruleset *rule.RuleSet, matcherIndex int, matched bool, err error) {
+			metrics.CountMatcherResult(ruleset, matcherIndex, matched, err)
+		},
+		OnRuleSetMatched: func(reqID string, ruleset *rule.RuleSet) { metrics.CountRuleSetMatch(ruleset) },
+	}
+	upstream := metrics.InstrumentUpstream(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler := Handler(upstream, HandlerConfig{DenyResponseCode: http.StatusNotFound}, func() []rule.RuleSet { return rulesets }, hooks)
+
+	for _, userAgent := range []string{"bot", "bot", "browser", "broken"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("User-Agent", userAgent)
+		handler(httptest.NewRecorder(), req)
+	}
+
+	if got := testutil.ToFloat64(metrics.requestsTotal.WithLabelValues("deny")); got != 2 {
+		t.Errorf("unexpected denied requests: want 2, but got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.requestsTotal.WithLabelValues("allow")); got != 2 {
+		t.Errorf("unexpected allowed requests: want 2, but got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.rulesetMatchesTotal.WithLabelValues("deny-bot")); got != 2 {
+		t.Errorf("unexpected ruleset matches: want 2, but got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.matcherMatchesTotal.WithLabelValues("deny-bot", "0")); got != 2 {
+		t.Errorf("unexpected matcher matches: want 2, but got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.matcherErrorsTotal.WithLabelValues("#1", "0")); got != 1 {
+		t.Errorf("unexpected matcher errors: want 1, but got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.errorsTotal); got != 1 {
+		t.Errorf("unexpected errors: want 1, but got %v", got)
+	}
+	if got := testutil.CollectAndCount(metrics.upstreamDuration); got != 1 {
+		t.Errorf("upstream latency is not collected")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/hnakamur/errstack"
+	"github.com/hnakamur/ltsvlog/v3"
+	"github.com/paralleltree/mastoshield/config"
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+type ruleSetHolder struct {
+	rulesets atomic.Pointer[[]rule.RuleSet]
+	// reloadMu serializes reloads triggered by the signal and the file watcher.
+	reloadMu sync.Mutex
+}
+
+func newRuleSetHolder(rulesets []rule.RuleSet) *ruleSetHolder {
+	h := &ruleSetHolder{}
+	h.Store(rulesets)
+	return h
+}
+
+func (h *ruleSetHolder) Load() []rule.RuleSet {
+	return *h.rulesets.Load()
+}
+
+func (h *ruleSetHolder) Store(rulesets []rule.RuleSet) {
+	h.rulesets.Store(&rulesets)
+}
+
+// reloadRuleSets replaces the rulesets only when the rule file is valid.
+// loader should be the one which loaded the current rulesets so that its state is kept.
+func reloadRuleSets(holder *ruleSetHolder, loader *config.RuleLoader, ruleFilePath string, trigger string) {
+	holder.reloadMu.Lock()
+	defer holder.reloadMu.Unlock()
+
+	rulesets, err := loadAccessControlConfig(loader, ruleFilePath)
+	if err != nil {
+		ltsvlog.Logger.Err(errstack.WithLV(err).
+			String("event", "ruleReload").
+			String("result", "failure").
+			String("trigger", trigger))
+		return
+	}
+	carryOverRateLimiters(holder.Load(), rulesets)
+	holder.Store(rulesets)
+	ltsvlog.Logger.Info().
+		String("event", "ruleReload").
+		String("result", "success").
+		String("trigger", trigger).
+		Int("rulesets", len(rulesets)).
+		Log()
+}
+
+// notifyReloadSignal starts relaying SIGHUP to the returned channel.
+// It should be called before the server starts so that no signal is missed.
+func notifyReloadSignal() (<-chan os.Signal, func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	return ch, func() { signal.Stop(ch) }
+}
+
+// carryOverRateLimiters passes the rate limit states to the rulesets with the same name.
+func carryOverRateLimiters(prev, next []rule.RuleSet) {
+	limiters := map[string]*rule.RateLimiter{}
+	for _, ruleset := range prev {
+		if ruleset.RateLimiter != nil {
+			limiters[ruleset.Name] = ruleset.RateLimiter
+		}
+	}
+	for _, ruleset := range next {
+		if prevLimiter, ok := limiters[ruleset.Name]; ok && ruleset.RateLimiter != nil {
+			ruleset.RateLimiter.CarryOver(prevLimiter)
+		}
+	}
+}
+
+func watchReloadSignal(ctx context.Context, ch <-chan os.Signal, onSignal func()) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			onSignal()
+		}
+	}
+}
+
+// watchFileChange calls onChange when the file is modified.
+// Only the rule file is watched, and the files referenced by it are reloaded on SIGHUP.
+func watchFileChange(ctx context.Context, path string, interval time.Duration, onChange func()) error {
+	stat := func() (os.FileInfo, error) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("stat file: %w", err)
+		}
+		return info, nil
+	}
+	last, err := stat()
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			current, err := stat()
+			if err != nil {
+				// the file may be replaced by an editor; retry at next tick
+				continue
+			}
+			if current.ModTime().Equal(last.ModTime()) && current.Size() == last.Size() {
+				continue
+			}
+			last = current
+			onChange()
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/mastoshield/config"
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestReloadRuleSets(t *testing.T) {
+	ruleFilePath := filepath.Join(t.TempDir(), "rules.yml")
+	writeRuleFile := func(body string) {
+		if err := os.WriteFile(ruleFilePath, []byte(body), 0o644); err != nil {
+			t.Fatalf("write rule file: %v", err)
+		}
+	}
+
+	writeRuleFile(`
+rulesets:
+  - action: deny
+    rules:
+      - source: user_agent
+        contains: bot
+`)
+	loader := config.NewRuleLoader()
+	rulesets, err := loadAccessControlConfig(loader, ruleFilePath)
+	if err != nil {
+		t.Fatalf("load rule file: %v", err)
+	}
+	holder := newRuleSetHolder(rulesets)
+
+	writeRuleFile(`
+rulesets:
+  - action: deny
+    rules:
+      - source: user_agent
+        contains: bot
+  - action: allow
+    rules:
+      - source: user_agent
+        contains: curl
+`)
+	reloadRuleSets(holder, loader, ruleFilePath, "test")
+	if got := len(holder.Load()); got != 2 {
+		t.Fatalf("rulesets are not reloaded: want 2 rulesets, but got %d", got)
+	}
+
+	writeRuleFile(`
+rulesets:
+  - action: unknown
+`)
+	reloadRuleSets(holder, loader, ruleFilePath, "test")
+	if got := len(holder.Load()); got != 2 {
+		t.Fatalf("invalid rule file replaced rulesets: want 2 rulesets, but got %d", got)
+	}
+}
+
+func TestWatchFileChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yml")
+	if err := os.WriteFile(path, []byte("a"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go watchFileChange(ctx, path, 10*time.Millisecond, func() {
+		changed <- struct{}{}
+	})
+
+	time.Sleep(30 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("ab"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatalf("file change is not detected")
+	}
+}
+
+func TestReloadRuleSets_CarryOverRateLimit(t *testing.T) {
+	ruleFilePath := filepath.Join(t.TempDir(), "rules.yml")
+	body := `
+rulesets:
+  - name: limit
+    action: rate_limit
+    rate_limit:
+      key: remote_ip
+      rate: 1
+      per: 1h
+    rules:
+      - source: user_agent
+        contains: bot
+`
+	if err := os.WriteFile(ruleFilePath, []byte(body), 0o644); err != nil {
+		t.Fatalf("write rule file: %v", err)
+	}
+	loader := config.NewRuleLoader()
+	rulesets, err := loadAccessControlConfig(loader, ruleFilePath)
+	if err != nil {
+		t.Fatalf("load rule file: %v", err)
+	}
+	holder := newRuleSetHolder(rulesets)
+
+	take := func() bool {
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+		req.RemoteAddr = "192.0.2.1:10000"
+		allowed, _, err := holder.Load()[0].RateLimiter.Take(rule.NewProxyRequest(req))
+		if err != nil {
+			t.Fatalf("take: %v", err)
+		}
+		return allowed
+	}
+	if !take() {
+		t.Fatalf("first request is limited")
+	}
+
+	reloadRuleSets(holder, loader, ruleFilePath, "test")
+	if take() {
+		t.Errorf("rate limit state is not carried over")
+	}
+}
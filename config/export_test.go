@@ -0,0 +1,11 @@
+package config
+
+import "github.com/paralleltree/mastoshield/httpsig"
+
+// KeyFetcher returns the key fetcher kept by the loader.
+func (l *RuleLoader) KeyFetcher() httpsig.KeyFetcher {
+	if l.keyFetcher == nil {
+		return nil
+	}
+	return l.keyFetcher.fetcher
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+type geoIPConfig struct {
+	// CountryDB is a MaxMind DB including countries, such as GeoLite2 Country.
+	CountryDB string `yaml:"country_db"`
+	// ASNDB is a MaxMind DB including autonomous systems, such as GeoLite2 ASN.
+	ASNDB string `yaml:"asn_db"`
+}
+
+func (b *ruleBuilder) loadCountryDatabase() (*rule.GeoIPDatabase, error) {
+	if b.countryDB == nil {
+		if b.geoIP.CountryDB == "" {
+			return nil, fmt.Errorf("geoip.country_db is not configured")
+		}
+		db, err := rule.LoadGeoIPDatabase(b.geoIP.CountryDB)
+		if err != nil {
+			return nil, fmt.Errorf("load country database: %w", err)
+		}
+		b.countryDB = db
+	}
+	return b.countryDB, nil
+}
+
+func (b *ruleBuilder) loadASNDatabase() (*rule.GeoIPDatabase, error) {
+	if b.asnDB == nil {
+		if b.geoIP.ASNDB == "" {
+			return nil, fmt.Errorf("geoip.asn_db is not configured")
+		}
+		db, err := rule.LoadGeoIPDatabase(b.geoIP.ASNDB)
+		if err != nil {
+			return nil, fmt.Errorf("load asn database: %w", err)
+		}
+		b.asnDB = db
+	}
+	return b.asnDB, nil
+}
+
+// parseASNs parses autonomous system numbers optionally prefixed with "AS".
+func parseASNs(values []string) ([]uint, error) {
+	asns := make([]uint, 0, len(values))
+	for _, value := range values {
+		number := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "AS")
+		asn, err := strconv.ParseUint(number, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parse asn %s: %w", value, err)
+		}
+		asns = append(asns, uint(asn))
+	}
+	return asns, nil
+}
@@ -4,13 +4,20 @@ import (
 	"fmt"
 
 	"github.com/caarlos0/env/v10"
+	"github.com/paralleltree/mastoshield/lib"
 )
 
 type ProxyConfig struct {
-	UpstreamEndpoint   string `env:"UPSTREAM_ENDPOINT,required"`
-	DenyResponseCode   int    `env:"DENY_RESPONSE_CODE" envDefault:"404"`
-	ListenPort         int    `env:"PORT" envDefault:"3000"`
-	ExitTimeoutSeconds int    `env:"EXIT_TIMEOUT" envDefault:"10"`
+	UpstreamEndpoint       string   `env:"UPSTREAM_ENDPOINT,required"`
+	DenyResponseCode       int      `env:"DENY_RESPONSE_CODE" envDefault:"404"`
+	ListenPort             int      `env:"PORT" envDefault:"3000"`
+	ExitTimeoutSeconds     int      `env:"EXIT_TIMEOUT" envDefault:"10"`
+	MetricsPort            int      `env:"METRICS_PORT" envDefault:"0"`
+	DryRun                 bool     `env:"DRY_RUN" envDefault:"false"`
+	TrustedProxies         []string `env:"TRUSTED_PROXIES" envSeparator:","`
+	IgnoreForwardedHeaders bool     `env:"IGNORE_FORWARDED_HEADERS" envDefault:"false"`
+	// ForwardedHeader is the header where the trusted proxies write the client address.
+	ForwardedHeader string `env:"FORWARDED_HEADER" envDefault:"X-Forwarded-For"`
 }
 
 func LoadProxyConfig() (*ProxyConfig, error) {
@@ -18,5 +25,8 @@ func LoadProxyConfig() (*ProxyConfig, error) {
 	if err := env.Parse(&c); err != nil {
 		return nil, fmt.Errorf("load environment variables: %w", err)
 	}
+	if c.TrustedProxies == nil {
+		c.TrustedProxies = lib.DefaultTrustedProxies
+	}
 	return &c, nil
 }
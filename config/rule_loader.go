@@ -1,31 +1,137 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/paralleltree/mastoshield/httpsig"
 	"github.com/paralleltree/mastoshield/rule"
 	"gopkg.in/yaml.v3"
 )
 
 type accessControlConfig struct {
-	RuleSets []ruleSetConfig `yaml:"rulesets"`
+	RuleSets  []ruleSetConfig  `yaml:"rulesets"`
+	Signature *signatureConfig `yaml:"signature"`
+	GeoIP     *geoIPConfig     `yaml:"geoip"`
+	// LocalDomains are the domains of the server behind the proxy.
+	LocalDomains stringList `yaml:"local_domains"`
 }
 
 type ruleSetConfig struct {
-	Action string       `yaml:"action"`
-	Rules  []ruleConfig `yaml:"rules"`
+	Name      string           `yaml:"name"`
+	ID        string           `yaml:"id"`
+	Action    string           `yaml:"action"`
+	Rules     []ruleConfig     `yaml:"rules"`
+	Response  *responseConfig  `yaml:"response"`
+	RateLimit *rateLimitConfig `yaml:"rate_limit"`
+}
+
+type rateLimitConfig struct {
+	Key     string        `yaml:"key"`
+	Rate    int           `yaml:"rate"`
+	Per     time.Duration `yaml:"per"`
+	Burst   int           `yaml:"burst"`
+	MaxKeys int           `yaml:"max_keys"`
+}
+
+const (
+	defaultRateLimitMaxKeys      = 10000
+	defaultDuplicateNoteDistance = 6
+	defaultDuplicateNoteEntries  = 10000
+)
+
+type responseConfig struct {
+	// Mode is a shorthand of the response. discard responds 202 Accepted with an empty body
+	// so that senders do not retry deliveries.
+	Mode    string            `yaml:"mode"`
+	Status  int               `yaml:"status"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
 }
 
 type ruleConfig struct {
-	Source     string `yaml:"source"`
-	Contains   string `yaml:"contains"`
-	StartsWith string `yaml:"starts_with"`
-	MoreThan   int    `yaml:"more_than"`
+	Source     string     `yaml:"source"`
+	Contains   string     `yaml:"contains"`
+	StartsWith string     `yaml:"starts_with"`
+	EndsWith   string     `yaml:"ends_with"`
+	Equals     string     `yaml:"equals"`
+	Matches    string     `yaml:"matches"`
+	IgnoreCase bool       `yaml:"ignore_case"`
+	MoreThan   *int       `yaml:"more_than"`
+	Status     stringList `yaml:"status"`
+
+	// Gt, Gte, Lt, Lte, Eq and Between configure count matchers.
+	// MoreThan and LessThan are aliases of Gt and Lt.
+	Gt       *int  `yaml:"gt"`
+	Gte      *int  `yaml:"gte"`
+	Lt       *int  `yaml:"lt"`
+	Lte      *int  `yaml:"lte"`
+	Eq       *int  `yaml:"eq"`
+	Between  []int `yaml:"between"`
+	LessThan *int  `yaml:"less_than"`
+
+	// Hashtags configures the hashtag matcher instead of the string pattern.
+	Hashtags stringList `yaml:"hashtags"`
+
+	// Normalize applies the normalization to the text tested by the string pattern.
+	Normalize normalizeSteps `yaml:"normalize"`
+	// Types configures the activity_type and object_type matchers.
+	Types stringList `yaml:"types"`
+	// Fields selects the fields tested by the object_text matcher. All fields are tested when omitted.
+	Fields stringList `yaml:"fields"`
+
+	// Domains and DomainFiles configure the actor_domain matcher.
+	Domains     stringList `yaml:"domains"`
+	DomainFiles stringList `yaml:"domain_files"`
+
+	// CIDRs and CIDRFiles configure the remote_ip matcher in addition to Contains.
+	CIDRs     stringList `yaml:"cidrs"`
+	CIDRFiles stringList `yaml:"cidr_files"`
+
+	// Blurhashes configures the attachment_blurhash matcher.
+	Blurhashes stringList `yaml:"blurhashes"`
+	// FingerprintFiles configures the media_fingerprint matcher with Distance.
+	FingerprintFiles stringList `yaml:"fingerprint_files"`
+
+	// Countries and ASNs configure the remote_country and remote_asn matchers.
+	Countries stringList `yaml:"countries"`
+	ASNs      stringList `yaml:"asns"`
+
+	// Window, Distance and MaxEntries configure the duplicate_note matcher.
+	// Distance also configures the media_fingerprint matcher.
+	Window     time.Duration `yaml:"window"`
+	Distance   *int          `yaml:"distance"`
+	MaxEntries int           `yaml:"max_entries"`
+
+	// Rules holds the nested rules of all_of, any_of and not groups.
+	Rules []ruleConfig `yaml:"rules"`
 }
 
 func LoadAccessControlConfig(f io.Reader) ([]rule.RuleSet, error) {
+	return NewRuleLoader().Load(f)
+}
+
+// RuleLoader loads rule files, keeping the state which outlives a rule file across reloads.
+// The key cache of signature verification is kept while the fetch settings are unchanged,
+// and the notes remembered by duplicate_note are kept per ruleset name while its window, distance and max_entries are unchanged.
+type RuleLoader struct {
+	mu                  sync.Mutex
+	keyFetcher          *sharedKeyFetcher
+	duplicateNoteStores map[string]sharedDuplicateNoteStore
+}
+
+func NewRuleLoader() *RuleLoader {
+	return &RuleLoader{}
+}
+
+// Load builds rulesets from the rule file. The kept state is updated only when the file is valid.
+func (l *RuleLoader) Load(f io.Reader) ([]rule.RuleSet, error) {
 	body, err := io.ReadAll(f)
 	if err != nil {
 		return nil, fmt.Errorf("read stream: %w", err)
@@ -36,63 +142,682 @@ func LoadAccessControlConfig(f io.Reader) ([]rule.RuleSet, error) {
 		return nil, fmt.Errorf("unmarshal yaml: %w", err)
 	}
 
-	rulesets, err := buildRuleSets(configBody.RuleSets)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	builder, err := newRuleBuilder(configBody, l.keyFetcher, l.duplicateNoteStores)
+	if err != nil {
+		return nil, err
+	}
+	rulesets, err := builder.buildRuleSets(configBody.RuleSets)
 	if err != nil {
 		return nil, fmt.Errorf("build rule sets: %w", err)
 	}
 	if err := validateRuleSets(rulesets); err != nil {
 		return nil, fmt.Errorf("validate rule sets: %w", err)
 	}
+	l.keyFetcher = builder.keyFetcher
+	l.duplicateNoteStores = builder.duplicateNoteStores
 	return rulesets, nil
 }
 
-func buildRuleSets(rulesetsConfig []ruleSetConfig) ([]rule.RuleSet, error) {
+// ruleBuilder holds resources shared by matchers built from a rule file.
+type ruleBuilder struct {
+	signatureVerifier *httpsig.Verifier
+	// keyFetcher is nil when fetching keys is disabled.
+	keyFetcher *sharedKeyFetcher
+	// duplicateNoteStores are the stores used by the duplicate_note matchers, keyed by the ruleset name and their order in it.
+	// prevDuplicateNoteStores are the stores of the previous load to be reused.
+	duplicateNoteStores     map[string]sharedDuplicateNoteStore
+	prevDuplicateNoteStores map[string]sharedDuplicateNoteStore
+	// rulesetName and duplicateNotes locate the duplicate_note matcher being built.
+	rulesetName    string
+	duplicateNotes int
+	// domainFiles caches domain lists by path so that a file shared by rules is read once.
+	domainFiles map[string][]string
+	// cidrFiles caches IP lists by path in the same way.
+	cidrFiles map[string][]string
+	// fingerprintFiles caches media fingerprint lists by path in the same way.
+	fingerprintFiles map[string][]rule.MediaFingerprint
+	// geoIP databases are loaded when a matcher requires them.
+	geoIP     geoIPConfig
+	countryDB *rule.GeoIPDatabase
+	asnDB     *rule.GeoIPDatabase
+	// localDomains is nil when local_domains is not configured.
+	localDomains *rule.DomainSet
+}
+
+// newRuleBuilder creates a builder. prevKeyFetcher and prevDuplicateNoteStores are reused when their settings are unchanged.
+func newRuleBuilder(conf accessControlConfig, prevKeyFetcher *sharedKeyFetcher, prevDuplicateNoteStores map[string]sharedDuplicateNoteStore) (*ruleBuilder, error) {
+	signatureConf := signatureConfig{}
+	if conf.Signature != nil {
+		signatureConf = *conf.Signature
+	}
+	verifier, keyFetcher, err := buildSignatureVerifier(signatureConf, prevKeyFetcher)
+	if err != nil {
+		return nil, fmt.Errorf("build signature verifier: %w", err)
+	}
+	builder := &ruleBuilder{
+		signatureVerifier:       verifier,
+		keyFetcher:              keyFetcher,
+		duplicateNoteStores:     map[string]sharedDuplicateNoteStore{},
+		prevDuplicateNoteStores: prevDuplicateNoteStores,
+		domainFiles:             map[string][]string{},
+		cidrFiles:               map[string][]string{},
+		fingerprintFiles:        map[string][]rule.MediaFingerprint{},
+	}
+	if conf.GeoIP != nil {
+		builder.geoIP = *conf.GeoIP
+	}
+	if len(conf.LocalDomains) > 0 {
+		localDomains, err := rule.NewDomainSet(conf.LocalDomains)
+		if err != nil {
+			return nil, fmt.Errorf("build local domains: %w", err)
+		}
+		builder.localDomains = localDomains
+	}
+	return builder, nil
+}
+
+func (b *ruleBuilder) buildRuleSets(rulesetsConfig []ruleSetConfig) ([]rule.RuleSet, error) {
 	rulesets := make([]rule.RuleSet, 0, len(rulesetsConfig))
-	for _, rulesetConfig := range rulesetsConfig {
+	for i, rulesetConfig := range rulesetsConfig {
 		ruleset := rule.RuleSet{}
 
+		name, err := resolveRuleSetName(i, rulesetConfig)
+		if err != nil {
+			return nil, err
+		}
+		ruleset.Name = name
+		b.rulesetName, b.duplicateNotes = name, 0
+
 		switch strings.ToLower(rulesetConfig.Action) {
 		case "allow":
 			ruleset.Action = rule.ACTION_ALLOW
 		case "deny":
 			ruleset.Action = rule.ACTION_DENY
+		case "log", "shadow":
+			ruleset.Action = rule.ACTION_LOG
+		case "rate_limit":
+			ruleset.Action = rule.ACTION_RATE_LIMIT
 		default:
 			return nil, fmt.Errorf("unexpected action type: %s", rulesetConfig.Action)
 		}
 
-		for _, ruleConfig := range rulesetConfig.Rules {
-			matcher, err := buildRuleMatcher(ruleConfig)
+		matchers, err := b.buildRuleMatchers(rulesetConfig.Rules)
+		if err != nil {
+			return nil, err
+		}
+		ruleset.Matchers = matchers
+
+		if rulesetConfig.Response != nil {
+			if ruleset.Action != rule.ACTION_DENY {
+				return nil, fmt.Errorf("response is only available for deny action: %s", ruleset.Name)
+			}
+			response, err := buildDenyResponse(*rulesetConfig.Response)
 			if err != nil {
-				return nil, fmt.Errorf("build rule matcher: %w", err)
+				return nil, fmt.Errorf("build response: %w", err)
 			}
-			ruleset.Matchers = append(ruleset.Matchers, matcher)
+			ruleset.Response = response
+		}
+
+		if ruleset.Action == rule.ACTION_RATE_LIMIT {
+			if rulesetConfig.RateLimit == nil {
+				return nil, fmt.Errorf("rate_limit is required for rate_limit action: %s", ruleset.Name)
+			}
+			limiter, err := buildRateLimiter(*rulesetConfig.RateLimit)
+			if err != nil {
+				return nil, fmt.Errorf("build rate limiter: %w", err)
+			}
+			ruleset.RateLimiter = limiter
+		} else if rulesetConfig.RateLimit != nil {
+			return nil, fmt.Errorf("rate_limit is only available for rate_limit action: %s", ruleset.Name)
 		}
 		rulesets = append(rulesets, ruleset)
 	}
 	return rulesets, nil
 }
 
-func buildRuleMatcher(ruleConfig ruleConfig) (rule.RuleMatcher, error) {
-	switch strings.ToLower(ruleConfig.Source) {
+func buildDenyResponse(responseConfig responseConfig) (*rule.DenyResponse, error) {
+	response := &rule.DenyResponse{
+		StatusCode: responseConfig.Status,
+		Header:     http.Header{},
+		Body:       []byte(responseConfig.Body),
+	}
+	for key, value := range responseConfig.Headers {
+		response.Header.Set(key, value)
+	}
+
+	switch strings.ToLower(responseConfig.Mode) {
+	case "":
+	case "discard":
+		if responseConfig.Status != 0 && responseConfig.Status != http.StatusAccepted {
+			return nil, fmt.Errorf("status cannot be changed in discard mode: %d", responseConfig.Status)
+		}
+		response.StatusCode = http.StatusAccepted
+	default:
+		return nil, fmt.Errorf("unexpected response mode: %s", responseConfig.Mode)
+	}
+
+	// zero leaves the status to DENY_RESPONSE_CODE
+	if response.StatusCode != 0 && (response.StatusCode < 100 || response.StatusCode > 599) {
+		return nil, fmt.Errorf("invalid status code: %d", response.StatusCode)
+	}
+	return response, nil
+}
+
+func buildRateLimiter(rateLimitConfig rateLimitConfig) (*rule.RateLimiter, error) {
+	burst := rateLimitConfig.Burst
+	if burst == 0 {
+		burst = rateLimitConfig.Rate
+	}
+	maxKeys := rateLimitConfig.MaxKeys
+	if maxKeys == 0 {
+		maxKeys = defaultRateLimitMaxKeys
+	}
+	return rule.NewRateLimiter(rule.RateLimitConfig{
+		Key:     rule.RateLimitKey(strings.ToLower(rateLimitConfig.Key)),
+		Rate:    rateLimitConfig.Rate,
+		Per:     rateLimitConfig.Per,
+		Burst:   burst,
+		MaxKeys: maxKeys,
+	}, nil)
+}
+
+func (b *ruleBuilder) buildDomainSet(ruleConfig ruleConfig) (*rule.DomainSet, error) {
+	if len(ruleConfig.Domains) == 0 && len(ruleConfig.DomainFiles) == 0 {
+		return nil, fmt.Errorf("domains or domain_files is required")
+	}
+	domains := append([]string{}, ruleConfig.Domains...)
+	for _, path := range ruleConfig.DomainFiles {
+		fileDomains, ok := b.domainFiles[path]
+		if !ok {
+			var err error
+			fileDomains, err = rule.LoadDomainFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("load domain file %s: %w", path, err)
+			}
+			b.domainFiles[path] = fileDomains
+		}
+		domains = append(domains, fileDomains...)
+	}
+	return rule.NewDomainSet(domains)
+}
+
+func (b *ruleBuilder) buildIPRangeSet(ruleConfig ruleConfig) (*rule.IPRangeSet, error) {
+	cidrs := append([]string{}, ruleConfig.CIDRs...)
+	// contains is kept for compatibility
+	if ruleConfig.Contains != "" {
+		cidrs = append(cidrs, ruleConfig.Contains)
+	}
+	if len(cidrs) == 0 && len(ruleConfig.CIDRFiles) == 0 {
+		return nil, fmt.Errorf("cidrs or cidr_files is required")
+	}
+	for _, path := range ruleConfig.CIDRFiles {
+		fileCIDRs, ok := b.cidrFiles[path]
+		if !ok {
+			var err error
+			fileCIDRs, err = rule.LoadIPListFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("load cidr file %s: %w", path, err)
+			}
+			b.cidrFiles[path] = fileCIDRs
+		}
+		cidrs = append(cidrs, fileCIDRs...)
+	}
+	return rule.NewIPRangeSet(cidrs)
+}
+
+func (b *ruleBuilder) buildMediaFingerprintMatcher(ruleConfig ruleConfig) (rule.RuleMatcher, error) {
+	if len(ruleConfig.FingerprintFiles) == 0 {
+		return nil, fmt.Errorf("fingerprint_files is required")
+	}
+	fingerprints := []rule.MediaFingerprint{}
+	for _, path := range ruleConfig.FingerprintFiles {
+		fileFingerprints, ok := b.fingerprintFiles[path]
+		if !ok {
+			var err error
+			fileFingerprints, err = rule.LoadMediaFingerprintFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("load fingerprint file %s: %w", path, err)
+			}
+			b.fingerprintFiles[path] = fileFingerprints
+		}
+		fingerprints = append(fingerprints, fileFingerprints...)
+	}
+	distance := 0
+	if ruleConfig.Distance != nil {
+		distance = *ruleConfig.Distance
+	}
+	return rule.NewMediaFingerprintMatcher(fingerprints, distance)
+}
+
+func buildCountCondition(ruleConfig ruleConfig) (rule.CountCondition, error) {
+	condition := rule.CountCondition{
+		GreaterThan:        ruleConfig.Gt,
+		GreaterThanOrEqual: ruleConfig.Gte,
+		LessThan:           ruleConfig.Lt,
+		LessThanOrEqual:    ruleConfig.Lte,
+		Equal:              ruleConfig.Eq,
+	}
+	aliases := []struct {
+		alias, key string
+		value      *int
+		target     **int
+	}{
+		{"more_than", "gt", ruleConfig.MoreThan, &condition.GreaterThan},
+		{"less_than", "lt", ruleConfig.LessThan, &condition.LessThan},
+	}
+	for _, a := range aliases {
+		if a.value == nil {
+			continue
+		}
+		if *a.target != nil {
+			return rule.CountCondition{}, fmt.Errorf("both %s and %s are specified", a.key, a.alias)
+		}
+		*a.target = a.value
+	}
+	if ruleConfig.Between != nil {
+		if len(ruleConfig.Between) != 2 {
+			return rule.CountCondition{}, fmt.Errorf("between requires a minimum and a maximum: %v", ruleConfig.Between)
+		}
+		if condition.GreaterThanOrEqual != nil || condition.LessThanOrEqual != nil {
+			return rule.CountCondition{}, fmt.Errorf("between cannot be combined with gte or lte")
+		}
+		condition.GreaterThanOrEqual = &ruleConfig.Between[0]
+		condition.LessThanOrEqual = &ruleConfig.Between[1]
+	}
+	if condition == (rule.CountCondition{}) {
+		return rule.CountCondition{}, fmt.Errorf("gt, gte, lt, lte, eq or between is required")
+	}
+	return condition, nil
+}
+
+// sharedDuplicateNoteStore is a store of duplicate_note shared by the rule files with the same settings.
+type sharedDuplicateNoteStore struct {
+	conf  rule.DuplicateNoteConfig
+	store *rule.DuplicateNoteStore
+}
+
+func (b *ruleBuilder) buildDuplicateNoteMatcher(ruleConfig ruleConfig) (rule.RuleMatcher, error) {
+	distance := defaultDuplicateNoteDistance
+	if ruleConfig.Distance != nil {
+		distance = *ruleConfig.Distance
+	}
+	maxEntries := ruleConfig.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = defaultDuplicateNoteEntries
+	}
+	condition, err := buildCountCondition(ruleConfig)
+	if err != nil {
+		return nil, err
+	}
+	conf := rule.DuplicateNoteConfig{
+		Window:     ruleConfig.Window,
+		Distance:   distance,
+		MaxEntries: maxEntries,
+	}
+
+	// the matchers are told apart by the ruleset name and their order in it
+	key := fmt.Sprintf("%s/%d", b.rulesetName, b.duplicateNotes)
+	b.duplicateNotes++
+	shared, ok := b.prevDuplicateNoteStores[key]
+	if !ok || shared.conf != conf {
+		store, err := rule.NewDuplicateNoteStore(conf, nil)
+		if err != nil {
+			return nil, err
+		}
+		shared = sharedDuplicateNoteStore{conf: conf, store: store}
+	}
+	b.duplicateNoteStores[key] = shared
+	return rule.NewDuplicateNoteMatcher(shared.store, condition)
+}
+
+// resolveRuleSetName returns the name of the ruleset.
+// id is accepted as an alias of name, and the position of the ruleset prefixed with # is used when neither is given.
+// Names starting with # are reserved so that they do not collide with the default names.
+func resolveRuleSetName(index int, rulesetConfig ruleSetConfig) (string, error) {
+	if rulesetConfig.Name != "" && rulesetConfig.ID != "" && rulesetConfig.Name != rulesetConfig.ID {
+		return "", fmt.Errorf("both name and id are specified: %s, %s", rulesetConfig.Name, rulesetConfig.ID)
+	}
+	name := rulesetConfig.Name
+	if name == "" {
+		name = rulesetConfig.ID
+	}
+	if name == "" {
+		return "#" + strconv.Itoa(index), nil
+	}
+	if strings.HasPrefix(name, "#") {
+		return "", fmt.Errorf("ruleset name cannot start with #: %s", name)
+	}
+	return name, nil
+}
+
+func (b *ruleBuilder) buildRuleMatchers(rulesConfig []ruleConfig) ([]rule.RuleMatcher, error) {
+	matchers := make([]rule.RuleMatcher, 0, len(rulesConfig))
+	for _, ruleConfig := range rulesConfig {
+		matcher, err := b.buildRuleMatcher(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build rule matcher: %w", err)
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+func (b *ruleBuilder) buildRuleMatcher(ruleConfig ruleConfig) (rule.RuleMatcher, error) {
+	source := strings.ToLower(ruleConfig.Source)
+	switch source {
+	case "all_of", "any_of", "not":
+		if len(ruleConfig.Rules) == 0 {
+			return nil, fmt.Errorf("empty rules in %s group", source)
+		}
+		matchers, err := b.buildRuleMatchers(ruleConfig.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("build %s group: %w", source, err)
+		}
+		switch source {
+		case "all_of":
+			return rule.NewAllOfMatcher(matchers)
+		case "any_of":
+			return rule.NewAnyOfMatcher(matchers)
+		default:
+			// not negates the conjunction of the nested rules
+			allOf, err := rule.NewAllOfMatcher(matchers)
+			if err != nil {
+				return nil, err
+			}
+			return rule.NewNotMatcher(allOf)
+		}
 	case "note_body":
-		return rule.NewNoteContentMatcher(ruleConfig.Contains)
-	case "mention_count":
-		return rule.NewMentionCountMatcher(ruleConfig.MoreThan)
+		pattern, err := buildStringPattern(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build pattern: %w", err)
+		}
+		return rule.NewNoteContentPatternMatcher(pattern)
+	case "activity_type":
+		return rule.NewActivityTypeMatcher(ruleConfig.Types)
+	case "object_type":
+		return rule.NewObjectTypeMatcher(ruleConfig.Types)
+	case "object_text":
+		pattern, err := buildStringPattern(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build pattern: %w", err)
+		}
+		fields := rule.AllObjectTextFields
+		if len(ruleConfig.Fields) > 0 {
+			fields = make([]rule.ObjectTextField, 0, len(ruleConfig.Fields))
+			for _, field := range ruleConfig.Fields {
+				fields = append(fields, rule.ObjectTextField(field))
+			}
+		}
+		return rule.NewObjectTextMatcher(pattern, fields)
+	case "local_mention_count", "remote_mention_count", "mention_domain_count":
+		condition, err := buildCountCondition(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build count condition: %w", err)
+		}
+		if source == "mention_domain_count" {
+			return rule.NewMentionDomainCountMatcher(condition)
+		}
+		if b.localDomains == nil {
+			return nil, fmt.Errorf("local_domains is required for %s", source)
+		}
+		if source == "local_mention_count" {
+			return rule.NewLocalMentionCountMatcher(b.localDomains, condition)
+		}
+		return rule.NewRemoteMentionCountMatcher(b.localDomains, condition)
+	case "mention_count", "hashtag_count", "custom_emoji_count", "link_count", "attachment_count":
+		condition, err := buildCountCondition(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build count condition: %w", err)
+		}
+		switch source {
+		case "mention_count":
+			return rule.NewMentionCountMatcher(condition)
+		case "hashtag_count":
+			return rule.NewHashtagCountMatcher(condition)
+		case "custom_emoji_count":
+			return rule.NewCustomEmojiCountMatcher(condition)
+		case "link_count":
+			return rule.NewLinkCountMatcher(condition)
+		default:
+			return rule.NewAttachmentCountMatcher(condition)
+		}
+	case "hashtag":
+		pattern, err := buildStringPattern(ruleConfig)
+		if len(ruleConfig.Hashtags) > 0 {
+			if err == nil {
+				return nil, fmt.Errorf("both hashtags and pattern are specified")
+			}
+			if !errors.Is(err, errNoPattern) {
+				return nil, fmt.Errorf("build pattern: %w", err)
+			}
+			return rule.NewHashtagMatcher(ruleConfig.Hashtags)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("build pattern: %w", err)
+		}
+		return rule.NewHashtagPatternMatcher(pattern)
+	case "duplicate_note":
+		return b.buildDuplicateNoteMatcher(ruleConfig)
+	case "actor_domain":
+		domains, err := b.buildDomainSet(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build domain set: %w", err)
+		}
+		return rule.NewActorDomainMatcher(domains)
+	case "link_domain":
+		domains, err := b.buildDomainSet(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build domain set: %w", err)
+		}
+		return rule.NewLinkDomainMatcher(domains)
+	case "link_shortener":
+		// additional shorteners can be given in domains or domain_files
+		ruleConfig.Domains = append(append(stringList{}, rule.URLShortenerDomains...), ruleConfig.Domains...)
+		domains, err := b.buildDomainSet(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build domain set: %w", err)
+		}
+		return rule.NewLinkDomainMatcher(domains)
+	case "link_lookalike":
+		return rule.NewLinkLookalikeMatcher()
+	case "attachment_media_type":
+		pattern, err := buildStringPattern(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build pattern: %w", err)
+		}
+		return rule.NewAttachmentMediaTypeMatcher(pattern)
+	case "attachment_blurhash":
+		return rule.NewAttachmentBlurhashMatcher(ruleConfig.Blurhashes)
+	case "attachment_host":
+		domains, err := b.buildDomainSet(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build domain set: %w", err)
+		}
+		return rule.NewAttachmentHostMatcher(domains)
+	case "attachment_missing_alt":
+		return rule.NewAttachmentMissingAltMatcher()
+	case "attachment_url":
+		pattern, err := buildStringPattern(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build pattern: %w", err)
+		}
+		return rule.NewAttachmentURLMatcher(pattern)
+	case "attachment_file_name":
+		pattern, err := buildStringPattern(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build pattern: %w", err)
+		}
+		return rule.NewAttachmentFileNameMatcher(pattern)
+	case "media_fingerprint":
+		return b.buildMediaFingerprintMatcher(ruleConfig)
 	case "actor":
-		return rule.NewActorMatcher(ruleConfig.StartsWith)
+		pattern, err := buildStringPattern(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build pattern: %w", err)
+		}
+		return rule.NewActorPatternMatcher(pattern)
 	case "user_agent", "useragent":
-		return rule.NewUserAgentMatcher(ruleConfig.Contains)
+		pattern, err := buildStringPattern(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build pattern: %w", err)
+		}
+		return rule.NewUserAgentPatternMatcher(pattern)
 	case "remote_ip":
-		return rule.NewRemoteIPAddressMatcher(ruleConfig.Contains) // Containedが適当な気はするけど...
+		ranges, err := b.buildIPRangeSet(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build ip range set: %w", err)
+		}
+		return rule.NewRemoteIPRangeMatcher(ranges)
+	case "remote_country":
+		db, err := b.loadCountryDatabase()
+		if err != nil {
+			return nil, err
+		}
+		return rule.NewRemoteCountryMatcher(db, ruleConfig.Countries)
+	case "remote_asn":
+		db, err := b.loadASNDatabase()
+		if err != nil {
+			return nil, err
+		}
+		asns, err := parseASNs(ruleConfig.ASNs)
+		if err != nil {
+			return nil, err
+		}
+		return rule.NewRemoteASNMatcher(db, asns)
+	case "signature":
+		if len(ruleConfig.Status) == 0 {
+			return nil, fmt.Errorf("status is required")
+		}
+		statuses := make([]rule.SignatureStatus, 0, len(ruleConfig.Status))
+		for _, s := range ruleConfig.Status {
+			status, err := rule.ParseSignatureStatus(s)
+			if err != nil {
+				return nil, err
+			}
+			statuses = append(statuses, status)
+		}
+		return rule.NewSignatureStatusMatcher(b.signatureVerifier, statuses)
+	case "signature_key_domain":
+		pattern, err := buildStringPattern(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("build pattern: %w", err)
+		}
+		return rule.NewSignatureKeyDomainMatcher(pattern)
+	case "signature_actor_mismatch":
+		return rule.NewSignatureActorMismatchMatcher()
 	}
 	return nil, fmt.Errorf("no matcher resolved: %s", ruleConfig.Source)
 }
 
+var errNoPattern = errors.New("no pattern specified")
+
+func buildStringPattern(ruleConfig ruleConfig) (rule.StringPattern, error) {
+	var normalizer *rule.TextNormalizer
+	if len(ruleConfig.Normalize) > 0 {
+		var err error
+		normalizer, err = rule.NewTextNormalizer(ruleConfig.Normalize)
+		if err != nil {
+			return nil, fmt.Errorf("build normalizer: %w", err)
+		}
+	}
+
+	patterns := []struct {
+		key     string
+		value   string
+		literal bool
+		factory func(string, bool) (rule.StringPattern, error)
+	}{
+		{"contains", ruleConfig.Contains, true, func(s string, i bool) (rule.StringPattern, error) { return rule.NewContainsPattern(s, i) }},
+		{"starts_with", ruleConfig.StartsWith, true, func(s string, i bool) (rule.StringPattern, error) { return rule.NewPrefixPattern(s, i) }},
+		{"ends_with", ruleConfig.EndsWith, true, func(s string, i bool) (rule.StringPattern, error) { return rule.NewSuffixPattern(s, i) }},
+		{"equals", ruleConfig.Equals, true, func(s string, i bool) (rule.StringPattern, error) { return rule.NewEqualsPattern(s, i) }},
+		{"matches", ruleConfig.Matches, false, func(s string, i bool) (rule.StringPattern, error) { return rule.NewRegexpPattern(s, i) }},
+	}
+
+	var pattern rule.StringPattern
+	specifiedKey := ""
+	for _, p := range patterns {
+		if p.value == "" {
+			continue
+		}
+		if specifiedKey != "" {
+			return nil, fmt.Errorf("both %s and %s are specified", specifiedKey, p.key)
+		}
+		value := p.value
+		if normalizer != nil && p.literal {
+			// literal patterns are normalized in the same way as the text
+			value = normalizer.NormalizePattern(value)
+		}
+		built, err := p.factory(value, ruleConfig.IgnoreCase)
+		if err != nil {
+			return nil, fmt.Errorf("build %s pattern: %w", p.key, err)
+		}
+		pattern = built
+		specifiedKey = p.key
+	}
+	if pattern == nil {
+		return nil, errNoPattern
+	}
+	if normalizer != nil {
+		return rule.NewNormalizedPattern(pattern, normalizer)
+	}
+	return pattern, nil
+}
+
 func validateRuleSets(rulesets []rule.RuleSet) error {
+	names := map[string]struct{}{}
 	for _, ruleset := range rulesets {
 		if len(ruleset.Matchers) == 0 {
 			return fmt.Errorf("empty matchers in ruleset")
 		}
+		if _, ok := names[ruleset.Name]; ok {
+			return fmt.Errorf("duplicate ruleset name: %s", ruleset.Name)
+		}
+		names[ruleset.Name] = struct{}{}
+	}
+	return nil
+}
+
+// stringList accepts either a single string or a sequence of strings.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = stringList{value.Value}
+		return nil
+	}
+	items := []string{}
+	if err := value.Decode(&items); err != nil {
+		return err
+	}
+	*l = items
+	return nil
+}
+
+// normalizeSteps accepts true for the default steps, or a list of steps.
+type normalizeSteps []rule.NormalizeStep
+
+func (l *normalizeSteps) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var enabled bool
+		if err := value.Decode(&enabled); err == nil {
+			if enabled {
+				*l = rule.DefaultNormalizeSteps
+			} else {
+				*l = nil
+			}
+			return nil
+		}
+	}
+	steps := stringList{}
+	if err := value.Decode(&steps); err != nil {
+		return err
+	}
+	*l = make(normalizeSteps, 0, len(steps))
+	for _, step := range steps {
+		*l = append(*l, rule.NormalizeStep(strings.ToLower(step)))
 	}
 	return nil
 }
@@ -0,0 +1,1542 @@
+package config_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+	"github.com/paralleltree/mastoshield/config"
+	"github.com/paralleltree/mastoshield/rule"
+	"gopkg.in/yaml.v3"
+)
+
+func TestLoadAccessControlConfig_Groups(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{
+			name: "nested groups",
+			body: `
+rulesets:
+  - action: deny
+    rules:
+      - source: any_of
+        rules:
+          - source: note_body
+            contains: foo
+          - source: note_body
+            contains: bar
+      - source: not
+        rules:
+          - source: actor
+            starts_with: https://example.com/
+`,
+		},
+		{
+			name: "empty any_of group",
+			body: `
+rulesets:
+  - action: deny
+    rules:
+      - source: any_of
+`,
+			wantErr: true,
+		},
+		{
+			name: "empty group nested in group",
+			body: `
+rulesets:
+  - action: deny
+    rules:
+      - source: all_of
+        rules:
+          - source: not
+            rules: []
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rulesets, err := config.LoadAccessControlConfig(strings.NewReader(tt.body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+			if len(rulesets) != 1 || len(rulesets[0].Matchers) != 2 {
+				t.Errorf("unexpected rulesets: %+v", rulesets)
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_StringPatterns(t *testing.T) {
+	cases := []struct {
+		name    string
+		rule    string
+		wantErr bool
+	}{
+		{
+			name: "regular expression",
+			rule: `{source: note_body, matches: "s+p+a+m+"}`,
+		},
+		{
+			name: "ends_with ignoring case",
+			rule: `{source: actor, ends_with: "/users/bob", ignore_case: true}`,
+		},
+		{
+			name: "equals",
+			rule: `{source: user_agent, equals: "curl/8.0.0"}`,
+		},
+		{
+			name:    "invalid regular expression",
+			rule:    `{source: note_body, matches: "(unclosed"}`,
+			wantErr: true,
+		},
+		{
+			name:    "multiple patterns",
+			rule:    `{source: note_body, contains: foo, matches: bar}`,
+			wantErr: true,
+		},
+		{
+			name:    "no pattern",
+			rule:    `{source: user_agent}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "rulesets:\n  - action: deny\n    rules:\n      - " + tt.rule + "\n"
+			_, err := config.LoadAccessControlConfig(strings.NewReader(body))
+			if tt.wantErr && err == nil {
+				t.Fatalf("expected error, but got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_Normalize(t *testing.T) {
+	cases := []struct {
+		name       string
+		rule       string
+		content    string
+		wantResult bool
+		wantErr    bool
+	}{
+		{
+			name:       "default steps",
+			rule:       `{source: note_body, contains: "Free Followers", normalize: true}`,
+			content:    "<p>ｆｒｅｅ f<span>ollowers</span></p>",
+			wantResult: true,
+		},
+		{
+			name:       "listed steps",
+			rule:       `{source: note_body, contains: "free followers", normalize: [html, confusables]}`,
+			content:    "<p>frее <b>followers</b></p>",
+			wantResult: true,
+		},
+		{
+			name:       "regular expression",
+			rule:       `{source: note_body, matches: "^free\\s+followers$", normalize: [html, casefold]}`,
+			content:    "<p>FREE followers</p>",
+			wantResult: true,
+		},
+		{
+			name:       "disabled",
+			rule:       `{source: note_body, contains: "free followers", normalize: false}`,
+			content:    "<p>free <b>followers</b></p>",
+			wantResult: false,
+		},
+		{
+			name:    "unknown step",
+			rule:    `{source: note_body, contains: "free followers", normalize: [unknown]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "rulesets:\n  - action: deny\n    rules:\n      - " + tt.rule + "\n"
+			rulesets, err := config.LoadAccessControlConfig(strings.NewReader(body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+			payload, err := json.Marshal(map[string]any{
+				"type":   "Create",
+				"actor":  "https://example.com/users/bob",
+				"object": map[string]any{"type": "Note", "content": tt.content},
+			})
+			if err != nil {
+				t.Fatalf("marshal json: %v", err)
+			}
+			req := httptest.NewRequest("POST", "/inbox", bytes.NewReader(payload))
+			got, err := rulesets[0].Matchers[0].Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_ObjectText(t *testing.T) {
+	payload := `{"type": "Create", "actor": "https://example.com/users/bob", "object": {"type": "Note", "summary": "ＳＰＡＭ", "content": "<p>hello</p>"}}`
+
+	cases := []struct {
+		name       string
+		rule       string
+		wantResult bool
+		wantErr    bool
+	}{
+		{
+			name:       "all fields",
+			rule:       `{source: object_text, contains: spam, normalize: true}`,
+			wantResult: true,
+		},
+		{
+			name:       "selected fields",
+			rule:       `{source: object_text, contains: spam, normalize: true, fields: [content, contentMap.ja]}`,
+			wantResult: false,
+		},
+		{
+			name:       "single field",
+			rule:       `{source: object_text, contains: spam, normalize: true, fields: summary}`,
+			wantResult: true,
+		},
+		{
+			name:    "unknown field",
+			rule:    `{source: object_text, contains: spam, fields: [body]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "rulesets:\n  - action: deny\n    rules:\n      - " + tt.rule + "\n"
+			rulesets, err := config.LoadAccessControlConfig(strings.NewReader(body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+			req := httptest.NewRequest("POST", "/inbox", strings.NewReader(payload))
+			got, err := rulesets[0].Matchers[0].Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_Types(t *testing.T) {
+	payload := `{"type": "Update", "actor": "https://example.com/users/bob", "object": {"type": "Question", "content": "<p>poll</p>"}}`
+
+	cases := []struct {
+		name       string
+		rule       string
+		wantResult bool
+		wantErr    bool
+	}{
+		{
+			name:       "activity type",
+			rule:       `{source: activity_type, types: [Create, Update]}`,
+			wantResult: true,
+		},
+		{
+			name:       "single object type",
+			rule:       `{source: object_type, types: Question}`,
+			wantResult: true,
+		},
+		{
+			name:       "other object type",
+			rule:       `{source: object_type, types: [Note, Article]}`,
+			wantResult: false,
+		},
+		{
+			name:    "missing types",
+			rule:    `{source: activity_type}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "rulesets:\n  - action: deny\n    rules:\n      - " + tt.rule + "\n"
+			rulesets, err := config.LoadAccessControlConfig(strings.NewReader(body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+			req := httptest.NewRequest("POST", "/inbox", strings.NewReader(payload))
+			got, err := rulesets[0].Matchers[0].Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_DuplicateNote(t *testing.T) {
+	cases := []struct {
+		name    string
+		rule    string
+		wantErr bool
+	}{
+		{
+			name: "duplicate note",
+			rule: `{source: duplicate_note, window: 10m, more_than: 5}`,
+		},
+		{
+			name: "exact duplicates",
+			rule: `{source: duplicate_note, window: 10m, more_than: 5, distance: 0, max_entries: 100}`,
+		},
+		{
+			name: "shared count condition",
+			rule: `{source: duplicate_note, window: 10m, gte: 3, lt: 100}`,
+		},
+		{
+			name:    "missing threshold",
+			rule:    `{source: duplicate_note, window: 10m}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing window",
+			rule:    `{source: duplicate_note, more_than: 5}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid distance",
+			rule:    `{source: duplicate_note, window: 10m, more_than: 5, distance: 65}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "rulesets:\n  - action: deny\n    rules:\n      - " + tt.rule + "\n"
+			_, err := config.LoadAccessControlConfig(strings.NewReader(body))
+			if tt.wantErr && err == nil {
+				t.Fatalf("expected error, but got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_RuleSetNames(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		wantNames []string
+		wantErr   bool
+	}{
+		{
+			name: "named rulesets",
+			body: `
+rulesets:
+  - name: deny-spam
+    action: deny
+    rules: [{source: note_body, contains: spam}]
+  - id: allow-partner
+    action: allow
+    rules: [{source: actor, starts_with: "https://partner.example.com/"}]
+  - action: deny
+    rules: [{source: user_agent, contains: bot}]
+`,
+			wantNames: []string{"deny-spam", "allow-partner", "#2"},
+		},
+		{
+			name: "explicit name looking like a position",
+			body: `
+rulesets:
+  - action: deny
+    rules: [{source: note_body, contains: spam}]
+  - name: "0"
+    action: deny
+    rules: [{source: note_body, contains: ham}]
+`,
+			wantNames: []string{"#0", "0"},
+		},
+		{
+			name: "reserved name",
+			body: `
+rulesets:
+  - name: "#1"
+    action: deny
+    rules: [{source: note_body, contains: spam}]
+  - action: deny
+    rules: [{source: note_body, contains: ham}]
+`,
+			wantErr: true,
+		},
+		{
+			name: "duplicate names",
+			body: `
+rulesets:
+  - name: deny-spam
+    action: deny
+    rules: [{source: note_body, contains: spam}]
+  - id: deny-spam
+    action: deny
+    rules: [{source: note_body, contains: ham}]
+`,
+			wantErr: true,
+		},
+		{
+			name: "different name and id",
+			body: `
+rulesets:
+  - name: deny-spam
+    id: deny-ham
+    action: deny
+    rules: [{source: note_body, contains: spam}]
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rulesets, err := config.LoadAccessControlConfig(strings.NewReader(tt.body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+			gotNames := make([]string, 0, len(rulesets))
+			for _, ruleset := range rulesets {
+				gotNames = append(gotNames, ruleset.Name)
+			}
+			if strings.Join(tt.wantNames, ",") != strings.Join(gotNames, ",") {
+				t.Errorf("unexpected names: want %v, but got %v", tt.wantNames, gotNames)
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_Response(t *testing.T) {
+	cases := []struct {
+		name           string
+		action         string
+		response       string
+		wantStatusCode int
+		wantErr        bool
+	}{
+		{
+			name:           "discard mode",
+			action:         "deny",
+			response:       `{mode: discard}`,
+			wantStatusCode: 202,
+		},
+		{
+			name:           "custom response",
+			action:         "deny",
+			response:       `{status: 403, headers: {Content-Type: text/plain}, body: forbidden}`,
+			wantStatusCode: 403,
+		},
+		{
+			name:           "body without status",
+			action:         "deny",
+			response:       `{headers: {Content-Type: text/plain}, body: forbidden}`,
+			wantStatusCode: 0,
+		},
+		{
+			name:     "status is changed in discard mode",
+			action:   "deny",
+			response: `{mode: discard, status: 404}`,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid status code",
+			action:   "deny",
+			response: `{status: 1000}`,
+			wantErr:  true,
+		},
+		{
+			name:     "response for allow action",
+			action:   "allow",
+			response: `{mode: discard}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "rulesets:\n  - action: " + tt.action + "\n    response: " + tt.response + "\n    rules: [{source: user_agent, contains: bot}]\n"
+			rulesets, err := config.LoadAccessControlConfig(strings.NewReader(body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+			if got := rulesets[0].Response.StatusCode; tt.wantStatusCode != got {
+				t.Errorf("unexpected status code: want %d, but got %d", tt.wantStatusCode, got)
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_RateLimit(t *testing.T) {
+	cases := []struct {
+		name      string
+		action    string
+		rateLimit string
+		wantErr   bool
+	}{
+		{
+			name:      "rate limit",
+			action:    "rate_limit",
+			rateLimit: `{key: actor_host, rate: 10, per: 1m, burst: 20}`,
+		},
+		{
+			name:      "burst defaults to rate",
+			action:    "rate_limit",
+			rateLimit: `{key: remote_ip, rate: 10, per: 1m}`,
+		},
+		{
+			name:    "missing rate limit",
+			action:  "rate_limit",
+			wantErr: true,
+		},
+		{
+			name:      "unknown key",
+			action:    "rate_limit",
+			rateLimit: `{key: user_agent, rate: 10, per: 1m}`,
+			wantErr:   true,
+		},
+		{
+			name:      "missing period",
+			action:    "rate_limit",
+			rateLimit: `{key: remote_ip, rate: 10}`,
+			wantErr:   true,
+		},
+		{
+			name:      "rate limit for deny action",
+			action:    "deny",
+			rateLimit: `{key: remote_ip, rate: 10, per: 1m}`,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "rulesets:\n  - action: " + tt.action + "\n    rules: [{source: user_agent, contains: bot}]\n"
+			if tt.rateLimit != "" {
+				body += "    rate_limit: " + tt.rateLimit + "\n"
+			}
+			rulesets, err := config.LoadAccessControlConfig(strings.NewReader(body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+			if rulesets[0].Action != rule.ACTION_RATE_LIMIT || rulesets[0].RateLimiter == nil {
+				t.Errorf("rate limiter is not configured: %+v", rulesets[0])
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_Signature(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	keyFile := filepath.Join(t.TempDir(), "keys.yml")
+	keyFileBody, err := yaml.Marshal(map[string]string{"https://example.com/users/alice#main-key": string(keyPEM)})
+	if err != nil {
+		t.Fatalf("marshal key file: %v", err)
+	}
+	if err := os.WriteFile(keyFile, keyFileBody, 0o644); err != nil {
+		t.Fatalf("write key file: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{
+			name: "signature matchers with key file",
+			body: `
+signature:
+  key_file: ` + keyFile + `
+  fetch_keys: false
+rulesets:
+  - action: deny
+    rules:
+      - source: signature
+        status: [missing, invalid]
+  - action: deny
+    rules:
+      - source: signature_key_domain
+        ends_with: .example.net
+  - action: deny
+    rules:
+      - source: signature_actor_mismatch
+`,
+		},
+		{
+			name: "single status",
+			body: `
+rulesets:
+  - action: deny
+    rules:
+      - source: signature
+        status: missing
+`,
+		},
+		{
+			name: "unknown status",
+			body: `
+rulesets:
+  - action: deny
+    rules:
+      - source: signature
+        status: unknown
+`,
+			wantErr: true,
+		},
+		{
+			name: "missing key file",
+			body: `
+signature:
+  key_file: ` + filepath.Join(t.TempDir(), "missing.yml") + `
+rulesets: []
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.LoadAccessControlConfig(strings.NewReader(tt.body))
+			if tt.wantErr && err == nil {
+				t.Fatalf("expected error, but got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+		})
+	}
+}
+
+func TestRuleLoader_KeepsKeyCache(t *testing.T) {
+	rules := `
+rulesets:
+  - action: deny
+    rules:
+      - source: signature
+        status: invalid
+`
+	loader := config.NewRuleLoader()
+	load := func(body string) {
+		t.Helper()
+		if _, err := loader.Load(strings.NewReader(body)); err != nil {
+			t.Fatalf("load config: %v", err)
+		}
+	}
+
+	load(rules)
+	first := loader.KeyFetcher()
+	if first == nil {
+		t.Fatalf("key fetcher is not built")
+	}
+
+	load(rules)
+	if loader.KeyFetcher() != first {
+		t.Errorf("key cache is discarded by reloading the same settings")
+	}
+
+	if _, err := loader.Load(strings.NewReader("signature:\n  fetch_timeout: 1s\nrulesets:\n  - action: unknown\n")); err == nil {
+		t.Fatalf("expected error, but got nil")
+	}
+	if loader.KeyFetcher() != first {
+		t.Errorf("key cache is replaced by an invalid rule file")
+	}
+
+	load("signature:\n  fetch_timeout: 1s\n" + rules)
+	if loader.KeyFetcher() == first {
+		t.Errorf("key cache is kept after the fetch settings changed")
+	}
+
+	load("signature:\n  fetch_keys: false\n" + rules)
+	if loader.KeyFetcher() != nil {
+		t.Errorf("key fetcher is kept after fetching keys is disabled")
+	}
+}
+
+func TestRuleLoader_KeepsDuplicateNotes(t *testing.T) {
+	loader := config.NewRuleLoader()
+	deliver := func(rules string, actor string) bool {
+		t.Helper()
+		rulesets, err := loader.Load(strings.NewReader("rulesets:\n" + rules))
+		if err != nil {
+			t.Fatalf("load config: %v", err)
+		}
+		payload, err := json.Marshal(map[string]any{
+			"type":   "Create",
+			"actor":  actor,
+			"object": map[string]any{"type": "Note", "content": "Check out this amazing offer at spam.example now"},
+		})
+		if err != nil {
+			t.Fatalf("marshal json: %v", err)
+		}
+		req := httptest.NewRequest("POST", "/inbox", bytes.NewReader(payload))
+		got, err := rulesets[0].Matchers[0].Test(rule.NewProxyRequest(req))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return got
+	}
+	ruleset := func(name, matcher string) string {
+		return "  - name: " + name + "\n    action: deny\n    rules:\n      - " + matcher + "\n"
+	}
+
+	cases := []struct {
+		name       string
+		rules      string
+		wantResult bool
+	}{
+		{
+			name:       "first delivery",
+			rules:      ruleset("spam-wave", `{source: duplicate_note, window: 10m, gt: 1}`),
+			wantResult: false,
+		},
+		{
+			name:       "threshold changed",
+			rules:      ruleset("spam-wave", `{source: duplicate_note, window: 10m, gte: 2}`),
+			wantResult: true,
+		},
+		{
+			name:       "ruleset renamed",
+			rules:      ruleset("spam", `{source: duplicate_note, window: 10m, gte: 2}`),
+			wantResult: false,
+		},
+		{
+			name:       "window changed",
+			rules:      ruleset("spam", `{source: duplicate_note, window: 5m, gte: 2}`),
+			wantResult: false,
+		},
+	}
+	for i, tt := range cases {
+		if got := deliver(tt.rules, fmt.Sprintf("https://example.com/users/%d", i)); tt.wantResult != got {
+			t.Errorf("unexpected result of %s: want %v, but got %v", tt.name, tt.wantResult, got)
+		}
+	}
+}
+
+func TestLoadAccessControlConfig_ActorDomain(t *testing.T) {
+	domainFile := filepath.Join(t.TempDir(), "domain_blocks.csv")
+	writeDomainFile := func(body string) {
+		if err := os.WriteFile(domainFile, []byte(body), 0o644); err != nil {
+			t.Fatalf("write domain file: %v", err)
+		}
+	}
+	body := `
+rulesets:
+  - action: deny
+    rules:
+      - source: actor_domain
+        domains: [inline.example]
+        domain_files: ` + domainFile + `
+`
+	test := func(actor string) bool {
+		rulesets, err := config.LoadAccessControlConfig(strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("load config: %v", err)
+		}
+		payload := `{"type": "Follow", "actor": "` + actor + `", "object": "https://example.net/users/alice"}`
+		req := httptest.NewRequest("POST", "/inbox", strings.NewReader(payload))
+		got, err := rulesets[0].Matchers[0].Test(rule.NewProxyRequest(req))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return got
+	}
+
+	writeDomainFile("#domain,#severity,#reject_media,#reject_reports,#public_comment,#obfuscate\nblocked.example,suspend,false,false,,false\n")
+	if !test("https://inline.example/users/bob") {
+		t.Errorf("inline domain is not matched")
+	}
+	if !test("https://sub.blocked.example/users/bob") {
+		t.Errorf("domain in file is not matched")
+	}
+
+	writeDomainFile("#domain,#severity,#reject_media,#reject_reports,#public_comment,#obfuscate\nanother.example,suspend,false,false,,false\n")
+	if test("https://sub.blocked.example/users/bob") {
+		t.Errorf("domain list is not reloaded")
+	}
+	if !test("https://another.example/users/bob") {
+		t.Errorf("domain list is not reloaded")
+	}
+
+	if _, err := config.LoadAccessControlConfig(strings.NewReader("rulesets:\n  - action: deny\n    rules: [{source: actor_domain}]\n")); err == nil {
+		t.Errorf("expected error for actor_domain without domains, but got nil")
+	}
+}
+
+func TestLoadAccessControlConfig_GeoIP(t *testing.T) {
+	writeDatabase := func(databaseType string, record mmdbtype.Map) string {
+		tree, err := mmdbwriter.New(mmdbwriter.Options{DatabaseType: databaseType, IncludeReservedNetworks: true})
+		if err != nil {
+			t.Fatalf("create tree: %v", err)
+		}
+		_, network, err := net.ParseCIDR("192.0.2.0/24")
+		if err != nil {
+			t.Fatalf("parse cidr: %v", err)
+		}
+		if err := tree.Insert(network, record); err != nil {
+			t.Fatalf("insert record: %v", err)
+		}
+		path := filepath.Join(t.TempDir(), databaseType+".mmdb")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("create file: %v", err)
+		}
+		defer f.Close()
+		if _, err := tree.WriteTo(f); err != nil {
+			t.Fatalf("write database: %v", err)
+		}
+		return path
+	}
+	countryDB := writeDatabase("GeoLite2-Country", mmdbtype.Map{"country": mmdbtype.Map{"iso_code": mmdbtype.String("JP")}})
+	asnDB := writeDatabase("GeoLite2-ASN", mmdbtype.Map{"autonomous_system_number": mmdbtype.Uint32(64500)})
+
+	cases := []struct {
+		name       string
+		body       string
+		wantResult bool
+		wantErr    bool
+	}{
+		{
+			name: "remote country",
+			body: `
+geoip:
+  country_db: ` + countryDB + `
+rulesets:
+  - action: deny
+    rules:
+      - source: remote_country
+        countries: [JP, DE]
+`,
+			wantResult: true,
+		},
+		{
+			name: "remote asn",
+			body: `
+geoip:
+  asn_db: ` + asnDB + `
+rulesets:
+  - action: deny
+    rules:
+      - source: remote_asn
+        asns: [AS64500, 64501]
+`,
+			wantResult: true,
+		},
+		{
+			name: "database is not configured",
+			body: `
+geoip:
+  country_db: ` + countryDB + `
+rulesets:
+  - action: deny
+    rules:
+      - source: remote_asn
+        asns: 64500
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid asn",
+			body: `
+geoip:
+  asn_db: ` + asnDB + `
+rulesets:
+  - action: deny
+    rules:
+      - source: remote_asn
+        asns: example
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rulesets, err := config.LoadAccessControlConfig(strings.NewReader(tt.body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = "192.0.2.1:10000"
+			got, err := rulesets[0].Matchers[0].Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_RemoteIP(t *testing.T) {
+	cidrFile := filepath.Join(t.TempDir(), "drop.txt")
+	if err := os.WriteFile(cidrFile, []byte("; DROP list\n198.51.100.0/24 ; SBL000000\n2001:db8::/32\n"), 0o644); err != nil {
+		t.Fatalf("write cidr file: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		rule       string
+		remoteAddr string
+		wantResult bool
+		wantErr    bool
+	}{
+		{
+			name:       "contains",
+			rule:       `{source: remote_ip, contains: 192.0.2.0/24}`,
+			remoteAddr: "192.0.2.1:10000",
+			wantResult: true,
+		},
+		{
+			name:       "cidrs",
+			rule:       `{source: remote_ip, cidrs: [192.0.2.0/24, 203.0.113.1]}`,
+			remoteAddr: "203.0.113.1:10000",
+			wantResult: true,
+		},
+		{
+			name:       "cidr files",
+			rule:       `{source: remote_ip, cidrs: 192.0.2.0/24, cidr_files: ` + cidrFile + `}`,
+			remoteAddr: "[2001:db8::1]:10000",
+			wantResult: true,
+		},
+		{
+			name:       "not in ranges",
+			rule:       `{source: remote_ip, cidr_files: [` + cidrFile + `]}`,
+			remoteAddr: "192.0.2.1:10000",
+			wantResult: false,
+		},
+		{
+			name:    "no ranges",
+			rule:    `{source: remote_ip}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid cidr",
+			rule:    `{source: remote_ip, cidrs: [192.0.2.0/33]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "rulesets:\n  - action: deny\n    rules:\n      - " + tt.rule + "\n"
+			rulesets, err := config.LoadAccessControlConfig(strings.NewReader(body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			got, err := rulesets[0].Matchers[0].Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_Links(t *testing.T) {
+	cases := []struct {
+		name       string
+		rule       string
+		content    string
+		wantResult bool
+		wantErr    bool
+	}{
+		{
+			name:       "link count",
+			rule:       `{source: link_count, more_than: 1}`,
+			content:    `<a href="https://a.example/">a</a> <a href="https://b.example/">b</a>`,
+			wantResult: true,
+		},
+		{
+			name:       "link domain",
+			rule:       `{source: link_domain, domains: ["*.spam.example"]}`,
+			content:    `<a href="https://www.spam.example/">a</a>`,
+			wantResult: true,
+		},
+		{
+			name:       "link shortener",
+			rule:       `{source: link_shortener}`,
+			content:    `<a href="https://bit.ly/abc">a</a>`,
+			wantResult: true,
+		},
+		{
+			name:       "additional link shortener",
+			rule:       `{source: link_shortener, domains: short.example}`,
+			content:    `<a href="https://short.example/abc">a</a>`,
+			wantResult: true,
+		},
+		{
+			name:       "link lookalike",
+			rule:       `{source: link_lookalike}`,
+			content:    `<a href="https://xn--pypal-4ve.com/">a</a>`,
+			wantResult: true,
+		},
+		{
+			name:    "link domain without domains",
+			rule:    `{source: link_domain}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "rulesets:\n  - action: deny\n    rules:\n      - " + tt.rule + "\n"
+			rulesets, err := config.LoadAccessControlConfig(strings.NewReader(body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+			payload, err := json.Marshal(map[string]any{
+				"type":   "Create",
+				"actor":  "https://example.com/users/bob",
+				"object": map[string]any{"type": "Note", "content": tt.content},
+			})
+			if err != nil {
+				t.Fatalf("marshal json: %v", err)
+			}
+			req := httptest.NewRequest("POST", "/inbox", bytes.NewReader(payload))
+			got, err := rulesets[0].Matchers[0].Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_Attachments(t *testing.T) {
+	attachment := []map[string]any{
+		{
+			"type":      "Document",
+			"mediaType": "image/png",
+			"url":       "https://files.spam.example/media/ad_banner.png",
+			"name":      nil,
+			"blurhash":  "LNJRyVof~qj[ayayj[j[%Mj[WBay",
+		},
+	}
+	cases := []struct {
+		name       string
+		rule       string
+		wantResult bool
+		wantErr    bool
+	}{
+		{
+			name:       "attachment count",
+			rule:       `{source: attachment_count, more_than: 0}`,
+			wantResult: true,
+		},
+		{
+			name:       "attachment media type",
+			rule:       `{source: attachment_media_type, starts_with: "image/"}`,
+			wantResult: true,
+		},
+		{
+			name:       "attachment blurhash",
+			rule:       `{source: attachment_blurhash, blurhashes: "LNJRyVof~qj[ayayj[j[%Mj[WBay"}`,
+			wantResult: true,
+		},
+		{
+			name:       "attachment host",
+			rule:       `{source: attachment_host, domains: ["*.spam.example"]}`,
+			wantResult: true,
+		},
+		{
+			name:       "attachment missing alt",
+			rule:       `{source: attachment_missing_alt}`,
+			wantResult: true,
+		},
+		{
+			name:       "attachment url",
+			rule:       `{source: attachment_url, contains: "/media/"}`,
+			wantResult: true,
+		},
+		{
+			name:       "attachment file name",
+			rule:       `{source: attachment_file_name, matches: "^ad_.+\\.png$"}`,
+			wantResult: true,
+		},
+		{
+			name:    "attachment blurhash without blurhashes",
+			rule:    `{source: attachment_blurhash}`,
+			wantErr: true,
+		},
+		{
+			name:    "attachment host without domains",
+			rule:    `{source: attachment_host}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "rulesets:\n  - action: deny\n    rules:\n      - " + tt.rule + "\n"
+			rulesets, err := config.LoadAccessControlConfig(strings.NewReader(body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+			payload, err := json.Marshal(map[string]any{
+				"type":   "Create",
+				"actor":  "https://example.com/users/bob",
+				"object": map[string]any{"type": "Note", "content": "", "attachment": attachment},
+			})
+			if err != nil {
+				t.Fatalf("marshal json: %v", err)
+			}
+			req := httptest.NewRequest("POST", "/inbox", bytes.NewReader(payload))
+			got, err := rulesets[0].Matchers[0].Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_MediaFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	fingerprintFile := filepath.Join(dir, "fingerprints.txt")
+	if err := os.WriteFile(fingerprintFile, []byte("# spam wave\nblurhash LNJRyVof~qj[ayayj[j[%Mj[WBay\n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	invalidFile := filepath.Join(dir, "invalid.txt")
+	if err := os.WriteFile(invalidFile, []byte("blurhash broken\n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		rule       string
+		blurhash   string
+		wantResult bool
+		wantErr    bool
+	}{
+		{
+			name:       "same blurhash",
+			rule:       `{source: media_fingerprint, fingerprint_files: ` + fingerprintFile + `}`,
+			blurhash:   "LNJRyVof~qj[ayayj[j[%Mj[WBay",
+			wantResult: true,
+		},
+		{
+			name:       "similar blurhash without distance",
+			rule:       `{source: media_fingerprint, fingerprint_files: ` + fingerprintFile + `}`,
+			blurhash:   "LNJRyVof~qj[ayayj[j[%Mj[WBaz",
+			wantResult: false,
+		},
+		{
+			name:       "similar blurhash within distance",
+			rule:       `{source: media_fingerprint, fingerprint_files: ` + fingerprintFile + `, distance: 2}`,
+			blurhash:   "LNJRyVof~qj[ayayj[j[%Mj[WBaz",
+			wantResult: true,
+		},
+		{
+			name:    "without fingerprint files",
+			rule:    `{source: media_fingerprint}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid fingerprint file",
+			rule:    `{source: media_fingerprint, fingerprint_files: ` + invalidFile + `}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing fingerprint file",
+			rule:    `{source: media_fingerprint, fingerprint_files: ` + filepath.Join(dir, "missing.txt") + `}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "rulesets:\n  - action: deny\n    rules:\n      - " + tt.rule + "\n"
+			rulesets, err := config.LoadAccessControlConfig(strings.NewReader(body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+			payload, err := json.Marshal(map[string]any{
+				"type":   "Create",
+				"actor":  "https://example.com/users/bob",
+				"object": map[string]any{"type": "Note", "content": "", "attachment": map[string]any{"type": "Image", "url": "https://example.com/a.png", "blurhash": tt.blurhash}},
+			})
+			if err != nil {
+				t.Fatalf("marshal json: %v", err)
+			}
+			req := httptest.NewRequest("POST", "/inbox", bytes.NewReader(payload))
+			got, err := rulesets[0].Matchers[0].Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_Tags(t *testing.T) {
+	tags := []map[string]any{
+		{"type": "Hashtag", "href": "https://example.com/tags/free", "name": "#Free"},
+		{"type": "Hashtag", "href": "https://example.com/tags/followers", "name": "#followers"},
+		{"type": "Emoji", "name": ":money:"},
+	}
+	cases := []struct {
+		name       string
+		rule       string
+		wantResult bool
+		wantErr    bool
+	}{
+		{
+			name:       "hashtag count more than",
+			rule:       `{source: hashtag_count, more_than: 1}`,
+			wantResult: true,
+		},
+		{
+			name:       "hashtag count less than",
+			rule:       `{source: hashtag_count, less_than: 2}`,
+			wantResult: false,
+		},
+		{
+			name:       "hashtag count in range",
+			rule:       `{source: hashtag_count, more_than: 1, less_than: 3}`,
+			wantResult: true,
+		},
+		{
+			name:       "custom emoji count",
+			rule:       `{source: custom_emoji_count, more_than: 0}`,
+			wantResult: true,
+		},
+		{
+			name:       "hashtag list",
+			rule:       `{source: hashtag, hashtags: [free, giveaway]}`,
+			wantResult: true,
+		},
+		{
+			name:       "hashtag pattern",
+			rule:       `{source: hashtag, matches: "^follow", ignore_case: true}`,
+			wantResult: true,
+		},
+		{
+			name:    "hashtag without list and pattern",
+			rule:    `{source: hashtag}`,
+			wantErr: true,
+		},
+		{
+			name:    "hashtag with list and pattern",
+			rule:    `{source: hashtag, hashtags: free, contains: free}`,
+			wantErr: true,
+		},
+		{
+			name:    "hashtag with list and invalid regexp",
+			rule:    `{source: hashtag, hashtags: free, matches: "("}`,
+			wantErr: true,
+		},
+		{
+			name:    "hashtag with list and invalid normalize step",
+			rule:    `{source: hashtag, hashtags: free, normalize: [unknown]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "rulesets:\n  - action: deny\n    rules:\n      - " + tt.rule + "\n"
+			rulesets, err := config.LoadAccessControlConfig(strings.NewReader(body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+			payload, err := json.Marshal(map[string]any{
+				"type":   "Create",
+				"actor":  "https://example.com/users/bob",
+				"object": map[string]any{"type": "Note", "content": "", "tag": tags},
+			})
+			if err != nil {
+				t.Fatalf("marshal json: %v", err)
+			}
+			req := httptest.NewRequest("POST", "/inbox", bytes.NewReader(payload))
+			got, err := rulesets[0].Matchers[0].Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_CountConditions(t *testing.T) {
+	// three mentions
+	tags := []map[string]any{
+		{"type": "Mention", "href": "https://example.com/users/a"},
+		{"type": "Mention", "href": "https://example.com/users/b"},
+		{"type": "Mention", "href": "https://example.com/users/c"},
+	}
+	cases := []struct {
+		name       string
+		rule       string
+		wantResult bool
+		wantErr    bool
+	}{
+		{name: "more_than", rule: `{source: mention_count, more_than: 2}`, wantResult: true},
+		{name: "gt", rule: `{source: mention_count, gt: 3}`, wantResult: false},
+		{name: "gte", rule: `{source: mention_count, gte: 3}`, wantResult: true},
+		{name: "lt", rule: `{source: mention_count, lt: 3}`, wantResult: false},
+		{name: "less_than", rule: `{source: mention_count, less_than: 4}`, wantResult: true},
+		{name: "lte", rule: `{source: mention_count, lte: 3}`, wantResult: true},
+		{name: "eq", rule: `{source: mention_count, eq: 3}`, wantResult: true},
+		{name: "between", rule: `{source: mention_count, between: [1, 3]}`, wantResult: true},
+		{name: "gt with lt", rule: `{source: mention_count, gt: 0, lt: 3}`, wantResult: false},
+		{name: "zero is explicit", rule: `{source: mention_count, eq: 0}`, wantResult: false},
+		{name: "missing comparison", rule: `{source: mention_count}`, wantErr: true},
+		{name: "misspelled comparison", rule: `{source: mention_count, more_then: 2}`, wantErr: true},
+		{name: "alias with canonical key", rule: `{source: mention_count, more_than: 2, gt: 2}`, wantErr: true},
+		{name: "between with one value", rule: `{source: mention_count, between: [1]}`, wantErr: true},
+		{name: "between with gte", rule: `{source: mention_count, between: [1, 3], gte: 2}`, wantErr: true},
+		{name: "reversed between", rule: `{source: mention_count, between: [3, 1]}`, wantErr: true},
+		{name: "eq with gt", rule: `{source: mention_count, eq: 3, gt: 1}`, wantErr: true},
+		{name: "negative count", rule: `{source: mention_count, gt: -1}`, wantErr: true},
+		{name: "link count", rule: `{source: link_count, eq: 0}`, wantResult: true},
+		{name: "attachment count", rule: `{source: attachment_count, lt: 1}`, wantResult: true},
+		{name: "hashtag count", rule: `{source: hashtag_count, lte: 0}`, wantResult: true},
+		{name: "custom emoji count", rule: `{source: custom_emoji_count, between: [0, 1]}`, wantResult: true},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "rulesets:\n  - action: deny\n    rules:\n      - " + tt.rule + "\n"
+			rulesets, err := config.LoadAccessControlConfig(strings.NewReader(body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+			payload, err := json.Marshal(map[string]any{
+				"type":   "Create",
+				"actor":  "https://example.com/users/bob",
+				"object": map[string]any{"type": "Note", "content": "", "tag": tags},
+			})
+			if err != nil {
+				t.Fatalf("marshal json: %v", err)
+			}
+			req := httptest.NewRequest("POST", "/inbox", bytes.NewReader(payload))
+			got, err := rulesets[0].Matchers[0].Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestLoadAccessControlConfig_MentionTargets(t *testing.T) {
+	payload, err := json.Marshal(map[string]any{
+		"type":  "Create",
+		"actor": "https://remote.example/users/spammer",
+		"to":    []string{"https://www.w3.org/ns/activitystreams#Public"},
+		"cc":    []string{"https://remote.example/users/spammer/followers"},
+		"object": map[string]any{
+			"type":    "Note",
+			"content": "",
+			"tag": []map[string]any{
+				{"type": "Mention", "href": "https://local.example/users/alice"},
+				{"type": "Mention", "href": "https://local.example/users/bob"},
+				{"type": "Mention", "href": "https://other.example/users/carol"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("marshal json: %v", err)
+	}
+
+	cases := []struct {
+		name         string
+		localDomains string
+		rule         string
+		wantResult   bool
+		wantErr      bool
+	}{
+		{
+			name:         "local mentions",
+			localDomains: "local.example",
+			rule:         `{source: local_mention_count, gte: 2}`,
+			wantResult:   true,
+		},
+		{
+			name:         "remote mentions",
+			localDomains: `[local.example, "*.local.example"]`,
+			rule:         `{source: remote_mention_count, gt: 1}`,
+			wantResult:   false,
+		},
+		{
+			name:       "mentioned domains without local domains",
+			rule:       `{source: mention_domain_count, eq: 2}`,
+			wantResult: true,
+		},
+		{
+			name:    "local mentions without local domains",
+			rule:    `{source: local_mention_count, gt: 0}`,
+			wantErr: true,
+		},
+		{
+			name:         "without comparison",
+			localDomains: "local.example",
+			rule:         `{source: remote_mention_count}`,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := "rulesets:\n  - action: deny\n    rules:\n      - " + tt.rule + "\n"
+			if tt.localDomains != "" {
+				body = "local_domains: " + tt.localDomains + "\n" + body
+			}
+			rulesets, err := config.LoadAccessControlConfig(strings.NewReader(body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config: %v", err)
+			}
+			req := httptest.NewRequest("POST", "/inbox", bytes.NewReader(payload))
+			got, err := rulesets[0].Matchers[0].Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
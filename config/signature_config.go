@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/paralleltree/mastoshield/httpsig"
+)
+
+type signatureConfig struct {
+	// KeyFile is a yaml file which maps keyId to a PEM encoded public key.
+	// Keys in the file take precedence over fetched keys.
+	KeyFile string `yaml:"key_file"`
+	// FetchKeys enables fetching keys from remote servers. Defaults to true.
+	FetchKeys    *bool         `yaml:"fetch_keys"`
+	FetchTimeout time.Duration `yaml:"fetch_timeout"`
+	CacheTTL     time.Duration `yaml:"cache_ttl"`
+	CacheMaxKeys int           `yaml:"cache_max_keys"`
+	// NegativeCacheTTL is the duration to remember keys which could not be fetched.
+	NegativeCacheTTL     time.Duration `yaml:"negative_cache_ttl"`
+	MaxConcurrentFetches int           `yaml:"max_concurrent_fetches"`
+
+	// MaxClockSkew is the allowed difference between the signing time and the current time.
+	MaxClockSkew time.Duration `yaml:"max_clock_skew"`
+}
+
+const (
+	defaultKeyFetchTimeout         = 5 * time.Second
+	defaultKeyCacheTTL             = time.Hour
+	defaultKeyCacheMaxKeys         = 10000
+	defaultKeyNegativeCacheTTL     = 5 * time.Minute
+	defaultKeyMaxConcurrentFetches = 16
+	defaultMaxClockSkew            = time.Hour
+)
+
+// keyFetcherConfig is the settings of fetching keys with defaults applied.
+type keyFetcherConfig struct {
+	timeout     time.Duration
+	ttl         time.Duration
+	maxKeys     int
+	negativeTTL time.Duration
+	maxFetches  int
+}
+
+// sharedKeyFetcher is a caching key fetcher shared by the rule files loaded with the same settings.
+type sharedKeyFetcher struct {
+	conf    keyFetcherConfig
+	fetcher httpsig.KeyFetcher
+}
+
+// buildSignatureVerifier builds a verifier and the key fetcher it uses.
+// prev is reused when its settings are the same, so that cached keys survive reloads.
+func buildSignatureVerifier(conf signatureConfig, prev *sharedKeyFetcher) (*httpsig.Verifier, *sharedKeyFetcher, error) {
+	fetchers := httpsig.ChainKeyFetcher{}
+
+	if conf.KeyFile != "" {
+		keys, err := httpsig.LoadKeyFile(conf.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load key file: %w", err)
+		}
+		fetchers = append(fetchers, keys)
+	}
+
+	var shared *sharedKeyFetcher
+	if conf.FetchKeys == nil || *conf.FetchKeys {
+		fetcherConf := keyFetcherConfig{
+			timeout:     conf.FetchTimeout,
+			ttl:         conf.CacheTTL,
+			maxKeys:     conf.CacheMaxKeys,
+			negativeTTL: conf.NegativeCacheTTL,
+			maxFetches:  conf.MaxConcurrentFetches,
+		}
+		if fetcherConf.timeout <= 0 {
+			fetcherConf.timeout = defaultKeyFetchTimeout
+		}
+		if fetcherConf.ttl <= 0 {
+			fetcherConf.ttl = defaultKeyCacheTTL
+		}
+		if fetcherConf.maxKeys <= 0 {
+			fetcherConf.maxKeys = defaultKeyCacheMaxKeys
+		}
+		if fetcherConf.negativeTTL <= 0 {
+			fetcherConf.negativeTTL = defaultKeyNegativeCacheTTL
+		}
+		if fetcherConf.maxFetches <= 0 {
+			fetcherConf.maxFetches = defaultKeyMaxConcurrentFetches
+		}
+
+		if prev != nil && prev.conf == fetcherConf {
+			shared = prev
+		} else {
+			fetcher, err := buildKeyFetcher(fetcherConf)
+			if err != nil {
+				return nil, nil, err
+			}
+			shared = &sharedKeyFetcher{conf: fetcherConf, fetcher: fetcher}
+		}
+		fetchers = append(fetchers, shared.fetcher)
+	}
+
+	maxClockSkew := conf.MaxClockSkew
+	if maxClockSkew <= 0 {
+		maxClockSkew = defaultMaxClockSkew
+	}
+	verifier, err := httpsig.NewVerifier(fetchers, maxClockSkew, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return verifier, shared, nil
+}
+
+func buildKeyFetcher(conf keyFetcherConfig) (httpsig.KeyFetcher, error) {
+	httpFetcher, err := httpsig.NewHTTPKeyFetcher(httpsig.NewPublicHTTPClient(conf.timeout), conf.maxFetches)
+	if err != nil {
+		return nil, fmt.Errorf("create key fetcher: %w", err)
+	}
+	fetcher, err := httpsig.NewCachingKeyFetcher(httpFetcher, conf.ttl, conf.negativeTTL, conf.maxKeys)
+	if err != nil {
+		return nil, fmt.Errorf("create key fetcher: %w", err)
+	}
+	return fetcher, nil
+}
@@ -0,0 +1,52 @@
+package httpsig_test
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/paralleltree/mastoshield/httpsig"
+)
+
+// signRequest signs the request with the given headers, setting the Digest header when body is given.
+func signRequest(r *http.Request, keyID string, key crypto.Signer, headers []string, body []byte) error {
+	if len(body) > 0 {
+		digest := sha256.Sum256(body)
+		r.Header.Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(digest[:]))
+	}
+
+	sig := &httpsig.Signature{
+		KeyID:   keyID,
+		Headers: headers,
+	}
+	signingString, err := sig.SigningString(r)
+	if err != nil {
+		return fmt.Errorf("build signing string: %w", err)
+	}
+
+	var signature []byte
+	switch key.Public().(type) {
+	case *rsa.PublicKey:
+		sig.Algorithm = "rsa-sha256"
+		hashed := sha256.Sum256([]byte(signingString))
+		signature, err = key.Sign(rand.Reader, hashed[:], crypto.SHA256)
+	case ed25519.PublicKey:
+		sig.Algorithm = "ed25519"
+		signature, err = key.Sign(rand.Reader, []byte(signingString), crypto.Hash(0))
+	default:
+		return fmt.Errorf("unsupported key type: %T", key.Public())
+	}
+	if err != nil {
+		return fmt.Errorf("sign: %w", err)
+	}
+
+	r.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="%s",headers="%s",signature="%s"`,
+		keyID, sig.Algorithm, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
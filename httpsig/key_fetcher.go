@@ -0,0 +1,339 @@
+package httpsig
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrKeyNotFound = errors.New("key not found")
+
+type KeyFetcher interface {
+	FetchKey(ctx context.Context, keyID string) (crypto.PublicKey, error)
+}
+
+// StaticKeyFetcher resolves keys from a fixed set of keys.
+type StaticKeyFetcher map[string]crypto.PublicKey
+
+func (f StaticKeyFetcher) FetchKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	key, ok := f[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	return key, nil
+}
+
+// LoadKeyFile loads a yaml file which maps keyId to a PEM encoded public key.
+func LoadKeyFile(path string) (StaticKeyFetcher, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	pems := map[string]string{}
+	if err := yaml.Unmarshal(body, &pems); err != nil {
+		return nil, fmt.Errorf("unmarshal yaml: %w", err)
+	}
+	fetcher := StaticKeyFetcher{}
+	for keyID, keyPEM := range pems {
+		key, err := ParsePublicKeyPEM(keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("parse key %s: %w", keyID, err)
+		}
+		fetcher[keyID] = key
+	}
+	return fetcher, nil
+}
+
+func ParsePublicKeyPEM(keyPEM string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("no pem block")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported pem block: %s", block.Type)
+	}
+}
+
+// ChainKeyFetcher tries fetchers in order while they report ErrKeyNotFound.
+type ChainKeyFetcher []KeyFetcher
+
+func (f ChainKeyFetcher) FetchKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	for _, fetcher := range f {
+		key, err := fetcher.FetchKey(ctx, keyID)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		return key, err
+	}
+	return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+}
+
+const maxKeyDocumentSize = 1 << 20
+
+type httpKeyFetcher struct {
+	client *http.Client
+	// slots limits the number of concurrent fetches.
+	slots chan struct{}
+}
+
+// NewHTTPKeyFetcher creates a fetcher which dereferences keyId and reads publicKeyPem from the actor or key document.
+// Only https keyIds are fetched, and fetches beyond maxConcurrentFetches wait for a running one to finish.
+// Use NewPublicHTTPClient for client so that keyIds cannot point to internal addresses.
+func NewHTTPKeyFetcher(client *http.Client, maxConcurrentFetches int) (*httpKeyFetcher, error) {
+	if client == nil {
+		return nil, fmt.Errorf("nil client")
+	}
+	if maxConcurrentFetches <= 0 {
+		return nil, fmt.Errorf("invalid max concurrent fetches: %d", maxConcurrentFetches)
+	}
+	return &httpKeyFetcher{
+		client: client,
+		slots:  make(chan struct{}, maxConcurrentFetches),
+	}, nil
+}
+
+func (f *httpKeyFetcher) FetchKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	u, err := url.Parse(keyID)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("%w: keyId is not an https url: %s", ErrKeyNotFound, keyID)
+	}
+
+	select {
+	case f.slots <- struct{}{}:
+		defer func() { <-f.slots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", keyID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", `application/activity+json, application/ld+json; profile="https://www.w3.org/ns/activitystreams"`)
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch key document: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", res.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxKeyDocumentSize))
+	if err != nil {
+		return nil, fmt.Errorf("read key document: %w", err)
+	}
+	keyPEM, err := findPublicKeyPEM(body, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePublicKeyPEM(keyPEM)
+}
+
+type publicKeyDocument struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPEM string `json:"publicKeyPem"`
+}
+
+// NewPublicHTTPClient creates a client which connects only to public addresses over https.
+// The addresses are checked when dialing, so that hosts resolving to or redirecting to internal addresses are rejected.
+func NewPublicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !isPublicAddr(addr) {
+				return fmt.Errorf("non-public address: %s", addr)
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to non-https url: %s", req.URL)
+			}
+			if len(via) >= 3 {
+				return fmt.Errorf("too many redirects")
+			}
+			return nil
+		},
+	}
+}
+
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// nonPublicPrefixes are ranges not covered by netip.Addr methods.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+func findPublicKeyPEM(body []byte, keyID string) (string, error) {
+	document := struct {
+		publicKeyDocument
+		PublicKey json.RawMessage `json:"publicKey"`
+	}{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return "", fmt.Errorf("unmarshal key document: %w", err)
+	}
+	if document.PublicKeyPEM != "" {
+		// a key document must be the key identified by keyId
+		if document.ID != keyID {
+			return "", fmt.Errorf("%w: key document id mismatch: %s", ErrKeyNotFound, document.ID)
+		}
+		return document.PublicKeyPEM, nil
+	}
+
+	keys := []publicKeyDocument{}
+	if err := json.Unmarshal(document.PublicKey, &keys); err != nil {
+		key := publicKeyDocument{}
+		if err := json.Unmarshal(document.PublicKey, &key); err != nil {
+			return "", fmt.Errorf("unmarshal public key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		// the key must be owned by the actor holding it
+		if key.ID == keyID && key.PublicKeyPEM != "" && (key.Owner == "" || key.Owner == document.ID) {
+			return key.PublicKeyPEM, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+}
+
+type cachedKey struct {
+	key crypto.PublicKey
+	// err is the cached failure of fetching the key.
+	err       error
+	expiresAt time.Time
+}
+
+type cachingKeyFetcher struct {
+	fetcher     KeyFetcher
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+	now         func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]cachedKey
+}
+
+// NewCachingKeyFetcher caches keys resolved by fetcher for ttl, and failures for negativeTTL, keeping at most maxEntries keys.
+func NewCachingKeyFetcher(fetcher KeyFetcher, ttl, negativeTTL time.Duration, maxEntries int) (*cachingKeyFetcher, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("invalid ttl: %v", ttl)
+	}
+	if negativeTTL <= 0 {
+		return nil, fmt.Errorf("invalid negative ttl: %v", negativeTTL)
+	}
+	if maxEntries <= 0 {
+		return nil, fmt.Errorf("invalid max entries: %d", maxEntries)
+	}
+	return &cachingKeyFetcher{
+		fetcher:     fetcher,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		maxEntries:  maxEntries,
+		now:         time.Now,
+		keys:        map[string]cachedKey{},
+	}, nil
+}
+
+func (f *cachingKeyFetcher) FetchKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	now := f.now()
+	f.mu.Lock()
+	cached, ok := f.keys[keyID]
+	f.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.key, cached.err
+	}
+
+	key, err := f.fetcher.FetchKey(ctx, keyID)
+	entry := cachedKey{key: key, expiresAt: now.Add(f.ttl)}
+	if err != nil {
+		if ctx.Err() != nil {
+			// the key itself was not tried
+			return nil, err
+		}
+		entry = cachedKey{err: err, expiresAt: now.Add(f.negativeTTL)}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.keys) >= f.maxEntries {
+		f.evict(now)
+	}
+	f.keys[keyID] = entry
+	return entry.key, entry.err
+}
+
+// evict removes expired keys, or an arbitrary key when none has expired.
+func (f *cachingKeyFetcher) evict(now time.Time) {
+	for keyID, cached := range f.keys {
+		if !now.Before(cached.expiresAt) {
+			delete(f.keys, keyID)
+		}
+	}
+	for keyID := range f.keys {
+		if len(f.keys) < f.maxEntries {
+			break
+		}
+		delete(f.keys, keyID)
+	}
+}
@@ -0,0 +1,186 @@
+package httpsig_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/mastoshield/httpsig"
+)
+
+func TestHTTPKeyFetcher(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	var requests atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/activity+json")
+		switch r.URL.Path {
+		case "/users/alice":
+			json.NewEncoder(w).Encode(map[string]any{
+				"id":   server.URL + "/users/alice",
+				"type": "Person",
+				"publicKey": map[string]any{
+					"id":           server.URL + "/users/alice#main-key",
+					"owner":        server.URL + "/users/alice",
+					"publicKeyPem": keyPEM,
+				},
+			})
+		case "/users/mallory":
+			// claims a key owned by another actor
+			json.NewEncoder(w).Encode(map[string]any{
+				"id":   server.URL + "/users/mallory",
+				"type": "Person",
+				"publicKey": map[string]any{
+					"id":           server.URL + "/users/mallory#main-key",
+					"owner":        server.URL + "/users/alice",
+					"publicKeyPem": keyPEM,
+				},
+			})
+		case "/keys/mallory":
+			// a key document whose id differs from keyId
+			json.NewEncoder(w).Encode(map[string]any{
+				"id":           server.URL + "/keys/alice",
+				"publicKeyPem": keyPEM,
+			})
+		default:
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer server.Close()
+
+	httpFetcher, err := httpsig.NewHTTPKeyFetcher(server.Client(), 4)
+	if err != nil {
+		t.Fatalf("create fetcher: %v", err)
+	}
+	fetcher, err := httpsig.NewCachingKeyFetcher(httpFetcher, time.Minute, time.Minute, 10)
+	if err != nil {
+		t.Fatalf("create fetcher: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		gotKey, err := fetcher.FetchKey(context.Background(), server.URL+"/users/alice#main-key")
+		if err != nil {
+			t.Fatalf("fetch key: %v", err)
+		}
+		if !key.PublicKey.Equal(gotKey) {
+			t.Errorf("unexpected key")
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("key is not cached: %d requests", got)
+	}
+
+	requests.Store(0)
+	for i := 0; i < 2; i++ {
+		if _, err := fetcher.FetchKey(context.Background(), server.URL+"/users/deleted#main-key"); err == nil {
+			t.Errorf("expected error for deleted actor, but got nil")
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("failure is not cached: %d requests", got)
+	}
+
+	for _, keyID := range []string{
+		server.URL + "/users/mallory#main-key",
+		server.URL + "/keys/mallory",
+	} {
+		if _, err := fetcher.FetchKey(context.Background(), keyID); !errors.Is(err, httpsig.ErrKeyNotFound) {
+			t.Errorf("unexpected error for %s: want %v, but got %v", keyID, httpsig.ErrKeyNotFound, err)
+		}
+	}
+}
+
+func TestHTTPKeyFetcher_RejectsNonHTTPS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s", r.URL)
+	}))
+	defer server.Close()
+
+	fetcher, err := httpsig.NewHTTPKeyFetcher(server.Client(), 4)
+	if err != nil {
+		t.Fatalf("create fetcher: %v", err)
+	}
+	for _, keyID := range []string{server.URL + "/users/alice#main-key", "file:///etc/passwd", "alice"} {
+		if _, err := fetcher.FetchKey(context.Background(), keyID); err == nil {
+			t.Errorf("expected error for %s, but got nil", keyID)
+		}
+	}
+}
+
+func TestNewPublicHTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s", r.URL)
+	}))
+	defer server.Close()
+
+	client := httpsig.NewPublicHTTPClient(time.Second)
+	fetcher, err := httpsig.NewHTTPKeyFetcher(client, 4)
+	if err != nil {
+		t.Fatalf("create fetcher: %v", err)
+	}
+	// the test server listens on a loopback address
+	if _, err := fetcher.FetchKey(context.Background(), server.URL+"/users/alice#main-key"); err == nil {
+		t.Errorf("expected error for loopback address, but got nil")
+	}
+}
+
+func TestHTTPKeyFetcher_ConcurrencyLimit(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	fetcher, err := httpsig.NewHTTPKeyFetcher(server.Client(), 1)
+	if err != nil {
+		t.Fatalf("create fetcher: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fetcher.FetchKey(context.Background(), server.URL+"/users/alice#main-key")
+	}()
+	<-started
+
+	// a fetch beyond the limit waits for a slot until its context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := fetcher.FetchKey(ctx, server.URL+"/users/bob#main-key"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error: want %v, but got %v", context.DeadlineExceeded, err)
+	}
+
+	// a waiting fetch proceeds once the running one finishes
+	waited := make(chan error)
+	go func() {
+		_, err := fetcher.FetchKey(context.Background(), server.URL+"/users/carol#main-key")
+		waited <- err
+	}()
+	close(release)
+	<-done
+	<-started
+	if err := <-waited; err == nil || errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: want fetch failure, but got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package httpsig
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Signature is a parsed draft-cavage HTTP Signature.
+type Signature struct {
+	KeyID     string
+	Algorithm string
+	Headers   []string
+	Signature []byte
+	Created   string
+	Expires   string
+}
+
+// ParseRequestSignature parses the Signature header, or the Authorization header with the Signature scheme.
+// It returns nil without error when the request is not signed.
+func ParseRequestSignature(r *http.Request) (*Signature, error) {
+	if header := r.Header.Get("Signature"); header != "" {
+		return ParseSignature(header)
+	}
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, params, found := strings.Cut(auth, " ")
+		if found && strings.EqualFold(scheme, "Signature") {
+			return ParseSignature(params)
+		}
+	}
+	return nil, nil
+}
+
+func ParseSignature(header string) (*Signature, error) {
+	params, err := parseParams(header)
+	if err != nil {
+		return nil, fmt.Errorf("parse parameters: %w", err)
+	}
+
+	sig := &Signature{
+		KeyID:     params["keyId"],
+		Algorithm: strings.ToLower(params["algorithm"]),
+		Created:   params["created"],
+		Expires:   params["expires"],
+	}
+	if sig.KeyID == "" {
+		return nil, fmt.Errorf("missing keyId")
+	}
+	if params["signature"] == "" {
+		return nil, fmt.Errorf("missing signature")
+	}
+	sig.Signature, err = base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return nil, fmt.Errorf("decode signature: %w", err)
+	}
+	if headers, ok := params["headers"]; ok {
+		sig.Headers = strings.Fields(strings.ToLower(headers))
+	} else {
+		// the spec defaults to (created), but Date is used by implementations predating it
+		sig.Headers = []string{"date"}
+	}
+	return sig, nil
+}
+
+// KeyHost returns the host of keyId.
+func (s *Signature) KeyHost() string {
+	u, err := url.Parse(s.KeyID)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+func (s *Signature) HasHeader(name string) bool {
+	for _, h := range s.Headers {
+		if h == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SigningString builds the string to be signed from the request.
+func (s *Signature) SigningString(r *http.Request) (string, error) {
+	lines := make([]string, 0, len(s.Headers))
+	for _, name := range s.Headers {
+		var value string
+		switch name {
+		case "(request-target)":
+			value = strings.ToLower(r.Method) + " " + r.URL.RequestURI()
+		case "(created)":
+			if _, err := strconv.ParseInt(s.Created, 10, 64); err != nil {
+				return "", fmt.Errorf("invalid created parameter: %s", s.Created)
+			}
+			value = s.Created
+		case "(expires)":
+			if _, err := strconv.ParseInt(s.Expires, 10, 64); err != nil {
+				return "", fmt.Errorf("invalid expires parameter: %s", s.Expires)
+			}
+			value = s.Expires
+		case "host":
+			value = r.Host
+			if value == "" {
+				value = r.Header.Get("Host")
+			}
+		default:
+			values := r.Header.Values(name)
+			if len(values) == 0 {
+				return "", fmt.Errorf("missing signed header: %s", name)
+			}
+			trimmed := make([]string, 0, len(values))
+			for _, v := range values {
+				trimmed = append(trimmed, strings.TrimSpace(v))
+			}
+			value = strings.Join(trimmed, ", ")
+		}
+		lines = append(lines, name+": "+value)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func parseParams(header string) (map[string]string, error) {
+	params := map[string]string{}
+	rest := strings.TrimSpace(header)
+	for rest != "" {
+		key, value, found := strings.Cut(rest, "=")
+		if !found {
+			return nil, fmt.Errorf("malformed parameter: %s", rest)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated value: %s", key)
+			}
+			params[key] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			end := strings.Index(value, ",")
+			if end < 0 {
+				end = len(value)
+			}
+			params[key] = strings.TrimSpace(value[:end])
+			rest = value[end:]
+		}
+		rest = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+		rest = strings.TrimSpace(rest)
+	}
+	return params, nil
+}
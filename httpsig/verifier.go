@@ -0,0 +1,202 @@
+package httpsig
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrKeyUnavailable is returned when the key could not be fetched for reasons other than its absence.
+var ErrKeyUnavailable = errors.New("key unavailable")
+
+// ErrVerificationAborted is returned when the context is done before the key is fetched.
+// It says nothing about the signature itself.
+var ErrVerificationAborted = errors.New("verification aborted")
+
+type Verifier struct {
+	fetcher KeyFetcher
+	// maxClockSkew is the allowed difference between the signing time and the current time.
+	maxClockSkew time.Duration
+	now          func() time.Time
+}
+
+// NewVerifier creates a verifier. If now is nil, time.Now is used.
+func NewVerifier(fetcher KeyFetcher, maxClockSkew time.Duration, now func() time.Time) (*Verifier, error) {
+	if fetcher == nil {
+		return nil, fmt.Errorf("nil key fetcher")
+	}
+	if maxClockSkew <= 0 {
+		return nil, fmt.Errorf("invalid max clock skew: %v", maxClockSkew)
+	}
+	if now == nil {
+		now = time.Now
+	}
+	return &Verifier{
+		fetcher:      fetcher,
+		maxClockSkew: maxClockSkew,
+		now:          now,
+	}, nil
+}
+
+// Verify verifies the signature of the request.
+// The host and either date or (created) must be signed, and the signing time must be within the clock skew.
+// When the request has a body, the Digest header must be signed and match the body.
+func (v *Verifier) Verify(ctx context.Context, r *http.Request, sig *Signature, body []byte) error {
+	for _, header := range []string{"(request-target)", "host"} {
+		if !sig.HasHeader(header) {
+			return fmt.Errorf("%s is not signed", header)
+		}
+	}
+	if !sig.HasHeader("date") && !sig.HasHeader("(created)") {
+		return fmt.Errorf("neither date nor (created) is signed")
+	}
+	if err := v.verifyTime(r, sig); err != nil {
+		return err
+	}
+	if len(body) > 0 {
+		if !sig.HasHeader("digest") {
+			return fmt.Errorf("digest is not signed")
+		}
+		if err := verifyDigest(r.Header.Get("Digest"), body); err != nil {
+			return fmt.Errorf("verify digest: %w", err)
+		}
+	}
+
+	signingString, err := sig.SigningString(r)
+	if err != nil {
+		return fmt.Errorf("build signing string: %w", err)
+	}
+
+	key, err := v.fetcher.FetchKey(ctx, sig.KeyID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("fetch key: %w: %w", ErrVerificationAborted, err)
+		}
+		if errors.Is(err, ErrKeyNotFound) {
+			return fmt.Errorf("fetch key: %w", err)
+		}
+		return fmt.Errorf("fetch key: %w: %w", ErrKeyUnavailable, err)
+	}
+	if err := verifySignature(key, sig.Algorithm, []byte(signingString), sig.Signature); err != nil {
+		return fmt.Errorf("verify signature: %w", err)
+	}
+	return nil
+}
+
+func (v *Verifier) verifyTime(r *http.Request, sig *Signature) error {
+	now := v.now()
+	if sig.HasHeader("date") {
+		date, err := http.ParseTime(r.Header.Get("Date"))
+		if err != nil {
+			return fmt.Errorf("invalid date header: %w", err)
+		}
+		if !v.withinClockSkew(now, date) {
+			return fmt.Errorf("date is out of range: %v", date)
+		}
+	}
+	if sig.Created != "" {
+		created, err := parseUnixTime(sig.Created)
+		if err != nil {
+			return fmt.Errorf("invalid created parameter: %w", err)
+		}
+		if !v.withinClockSkew(now, created) {
+			return fmt.Errorf("created is out of range: %v", created)
+		}
+	}
+	if sig.Expires != "" {
+		expires, err := parseUnixTime(sig.Expires)
+		if err != nil {
+			return fmt.Errorf("invalid expires parameter: %w", err)
+		}
+		if now.After(expires.Add(v.maxClockSkew)) {
+			return fmt.Errorf("signature expired at %v", expires)
+		}
+	}
+	return nil
+}
+
+func (v *Verifier) withinClockSkew(now, t time.Time) bool {
+	d := now.Sub(t)
+	return -v.maxClockSkew <= d && d <= v.maxClockSkew
+}
+
+func parseUnixTime(s string) (time.Time, error) {
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
+
+func verifySignature(key crypto.PublicKey, algorithm string, message, signature []byte) error {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		var h crypto.Hash
+		switch algorithm {
+		case "", "hs2019", "rsa-sha256":
+			h = crypto.SHA256
+		case "rsa-sha512":
+			h = crypto.SHA512
+		default:
+			return fmt.Errorf("unsupported algorithm for rsa key: %s", algorithm)
+		}
+		hasher := h.New()
+		hasher.Write(message)
+		return rsa.VerifyPKCS1v15(key, h, hasher.Sum(nil), signature)
+	case ed25519.PublicKey:
+		switch algorithm {
+		case "", "hs2019", "ed25519":
+		default:
+			return fmt.Errorf("unsupported algorithm for ed25519 key: %s", algorithm)
+		}
+		if !ed25519.Verify(key, message, signature) {
+			return fmt.Errorf("ed25519 verification error")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported key type: %T", key)
+	}
+}
+
+func verifyDigest(header string, body []byte) error {
+	if header == "" {
+		return fmt.Errorf("missing digest header")
+	}
+	for _, digest := range strings.Split(header, ",") {
+		algorithm, encoded, found := strings.Cut(strings.TrimSpace(digest), "=")
+		if !found {
+			continue
+		}
+		var h hash.Hash
+		switch strings.ToUpper(algorithm) {
+		case "SHA-256":
+			h = sha256.New()
+		case "SHA-512":
+			h = sha512.New()
+		default:
+			continue
+		}
+		want, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("decode digest: %w", err)
+		}
+		h.Write(body)
+		if !bytes.Equal(want, h.Sum(nil)) {
+			return fmt.Errorf("digest mismatch")
+		}
+		return nil
+	}
+	return fmt.Errorf("no supported digest algorithm: %s", header)
+}
@@ -0,0 +1,244 @@
+package httpsig_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/mastoshield/httpsig"
+)
+
+func TestVerifier_Verify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+
+	const (
+		rsaKeyID     = "https://example.com/users/alice#main-key"
+		ed25519KeyID = "https://example.com/users/alice#ed25519-key"
+		otherKeyID   = "https://example.com/users/bob#main-key"
+	)
+	fetcher := httpsig.StaticKeyFetcher{
+		rsaKeyID:     rsaKey.Public(),
+		ed25519KeyID: ed25519Key.Public(),
+		otherKeyID:   otherKey.Public(),
+	}
+	signedAt := time.Date(2024, 2, 18, 4, 52, 27, 0, time.UTC)
+	verifier, err := httpsig.NewVerifier(fetcher, time.Hour, func() time.Time { return signedAt })
+	if err != nil {
+		t.Fatalf("create verifier: %v", err)
+	}
+
+	body := []byte(`{"type":"Create"}`)
+	defaultHeaders := []string{"(request-target)", "host", "date", "digest"}
+
+	cases := []struct {
+		name         string
+		keyID        string
+		key          crypto.Signer
+		headers      []string
+		date         string
+		tamper       func(r *http.Request)
+		verifiedBody []byte
+		wantErr      bool
+		wantErrIs    error
+	}{
+		{
+			name:    "valid rsa signature",
+			keyID:   rsaKeyID,
+			key:     rsaKey,
+			headers: defaultHeaders,
+		},
+		{
+			name:    "valid ed25519 signature",
+			keyID:   ed25519KeyID,
+			key:     ed25519Key,
+			headers: defaultHeaders,
+		},
+		{
+			name:    "signed with another key",
+			keyID:   otherKeyID,
+			key:     rsaKey,
+			headers: defaultHeaders,
+			wantErr: true,
+		},
+		{
+			name:    "signed header is modified",
+			keyID:   rsaKeyID,
+			key:     rsaKey,
+			headers: defaultHeaders,
+			tamper: func(r *http.Request) {
+				r.Header.Set("Date", "Sun, 18 Feb 2024 05:00:00 GMT")
+			},
+			wantErr: true,
+		},
+		{
+			name:         "body is modified",
+			keyID:        rsaKeyID,
+			key:          rsaKey,
+			headers:      defaultHeaders,
+			verifiedBody: []byte(`{"type":"Delete"}`),
+			wantErr:      true,
+		},
+		{
+			name:    "digest is not signed",
+			keyID:   rsaKeyID,
+			key:     rsaKey,
+			headers: []string{"(request-target)", "host", "date"},
+			wantErr: true,
+		},
+		{
+			name:    "host is not signed",
+			keyID:   rsaKeyID,
+			key:     rsaKey,
+			headers: []string{"(request-target)", "date", "digest"},
+			wantErr: true,
+		},
+		{
+			name:    "neither date nor created is signed",
+			keyID:   rsaKeyID,
+			key:     rsaKey,
+			headers: []string{"(request-target)", "host", "digest"},
+			wantErr: true,
+		},
+		{
+			name:    "date within clock skew",
+			keyID:   rsaKeyID,
+			key:     rsaKey,
+			headers: defaultHeaders,
+			date:    "Sun, 18 Feb 2024 05:30:00 GMT",
+		},
+		{
+			name:    "date is too old",
+			keyID:   rsaKeyID,
+			key:     rsaKey,
+			headers: defaultHeaders,
+			date:    "Sun, 18 Feb 2024 03:00:00 GMT",
+			wantErr: true,
+		},
+		{
+			name:    "date is in the future",
+			keyID:   rsaKeyID,
+			key:     rsaKey,
+			headers: defaultHeaders,
+			date:    "Sun, 18 Feb 2024 06:00:00 GMT",
+			wantErr: true,
+		},
+		{
+			name:    "signature is expired",
+			keyID:   rsaKeyID,
+			key:     rsaKey,
+			headers: defaultHeaders,
+			tamper: func(r *http.Request) {
+				r.Header.Set("Signature", r.Header.Get("Signature")+`,expires="1708221600"`)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "created is in the future",
+			keyID:   rsaKeyID,
+			key:     rsaKey,
+			headers: defaultHeaders,
+			tamper: func(r *http.Request) {
+				r.Header.Set("Signature", r.Header.Get("Signature")+`,created="1708243200"`)
+			},
+			wantErr: true,
+		},
+		{
+			name:      "unknown key",
+			keyID:     "https://example.net/users/carol#main-key",
+			key:       rsaKey,
+			headers:   defaultHeaders,
+			wantErr:   true,
+			wantErrIs: httpsig.ErrKeyNotFound,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "https://example.net/inbox", bytes.NewBuffer(body))
+			if err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+			date := "Sun, 18 Feb 2024 04:52:27 GMT"
+			if tt.date != "" {
+				date = tt.date
+			}
+			req.Header.Set("Date", date)
+			if err := signRequest(req, tt.keyID, tt.key, tt.headers, body); err != nil {
+				t.Fatalf("sign request: %v", err)
+			}
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+
+			sig, err := httpsig.ParseRequestSignature(req)
+			if err != nil {
+				t.Fatalf("parse signature: %v", err)
+			}
+			verifiedBody := body
+			if tt.verifiedBody != nil {
+				verifiedBody = tt.verifiedBody
+			}
+
+			err = verifier.Verify(context.Background(), req, sig, verifiedBody)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+					t.Errorf("unexpected error: want %v, but got %v", tt.wantErrIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseSignature(t *testing.T) {
+	header := `keyId="https://example.com/users/alice#main-key",algorithm="rsa-sha256",headers="(request-target) host date digest",signature="c2lnbmF0dXJl"`
+	sig, err := httpsig.ParseSignature(header)
+	if err != nil {
+		t.Fatalf("parse signature: %v", err)
+	}
+	if sig.KeyID != "https://example.com/users/alice#main-key" {
+		t.Errorf("unexpected keyId: %s", sig.KeyID)
+	}
+	if sig.KeyHost() != "example.com" {
+		t.Errorf("unexpected key host: %s", sig.KeyHost())
+	}
+	if sig.Algorithm != "rsa-sha256" {
+		t.Errorf("unexpected algorithm: %s", sig.Algorithm)
+	}
+	if len(sig.Headers) != 4 || sig.Headers[0] != "(request-target)" {
+		t.Errorf("unexpected headers: %v", sig.Headers)
+	}
+	if string(sig.Signature) != "signature" {
+		t.Errorf("unexpected signature: %s", sig.Signature)
+	}
+
+	for _, invalid := range []string{`algorithm="rsa-sha256"`, `keyId="a"`, `keyId="a",signature="!!"`, `keyId="a`} {
+		if _, err := httpsig.ParseSignature(invalid); err == nil {
+			t.Errorf("expected error for %s, but got nil", invalid)
+		}
+	}
+}
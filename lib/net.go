@@ -4,19 +4,175 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
 	"strings"
 )
 
-func ResolveClientIP(r *http.Request) (string, error) {
-	if prior := r.Header.Get("X-Forwarded-For"); prior != "" {
-		addrs := strings.Split(prior, ",")
-		if len(addrs) > 0 {
-			return addrs[0], nil
+// DefaultTrustedProxies is the loopback and private networks where reverse proxies usually run.
+var DefaultTrustedProxies = []string{
+	"127.0.0.0/8",
+	"::1/128",
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"fc00::/7",
+}
+
+// DefaultForwardedHeader is the forwarding header set by most reverse proxies.
+const DefaultForwardedHeader = "X-Forwarded-For"
+
+var DefaultClientIPResolver = mustNewClientIPResolver(DefaultTrustedProxies, DefaultForwardedHeader, false)
+
+type ClientIPResolver struct {
+	trustedProxies []netip.Prefix
+	// header is the forwarding header written by the trusted proxies.
+	header        string
+	ignoreHeaders bool
+}
+
+// NewClientIPResolver creates a resolver which trusts forwarding headers only when they are set by trustedProxies.
+// Only header, one of Forwarded, X-Forwarded-For and X-Real-IP, is read, since the proxies may pass the others from clients through.
+// When ignoreHeaders is true, the address of the peer is always used.
+func NewClientIPResolver(trustedProxies []string, header string, ignoreHeaders bool) (*ClientIPResolver, error) {
+	header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+	switch header {
+	case "Forwarded", "X-Forwarded-For", "X-Real-Ip":
+	default:
+		return nil, fmt.Errorf("unsupported forwarded header: %s", header)
+	}
+	prefixes := make([]netip.Prefix, 0, len(trustedProxies))
+	for _, s := range trustedProxies {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		prefix, err := parsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("parse trusted proxy: %w", err)
 		}
+		prefixes = append(prefixes, prefix)
 	}
-	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	return &ClientIPResolver{
+		trustedProxies: prefixes,
+		header:         header,
+		ignoreHeaders:  ignoreHeaders,
+	}, nil
+}
+
+func mustNewClientIPResolver(trustedProxies []string, header string, ignoreHeaders bool) *ClientIPResolver {
+	resolver, err := NewClientIPResolver(trustedProxies, header, ignoreHeaders)
+	if err != nil {
+		panic(err)
+	}
+	return resolver
+}
+
+func ResolveClientIP(r *http.Request) (string, error) {
+	return DefaultClientIPResolver.Resolve(r)
+}
+
+// Resolve walks the forwarding chain from the nearest hop and returns the first address which is not a trusted proxy.
+// Only the configured forwarding header is looked up.
+func (c *ClientIPResolver) Resolve(r *http.Request) (string, error) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
 		return "", fmt.Errorf("split host and port: %w", err)
 	}
-	return clientIP, nil
+	peer, err := netip.ParseAddr(host)
+	if err != nil {
+		return "", fmt.Errorf("parse remote addr: %w", err)
+	}
+	peer = peer.Unmap()
+	if c.ignoreHeaders || !c.isTrusted(peer) {
+		return peer.String(), nil
+	}
+
+	client := peer
+	hops := forwardedHops(r.Header, c.header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := parseHop(hops[i])
+		if err != nil {
+			// the trusted proxy forwarded an unknown or obfuscated address
+			break
+		}
+		client = hop
+		if !c.isTrusted(hop) {
+			break
+		}
+	}
+	return client.String(), nil
+}
+
+func (c *ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range c.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func forwardedHops(header http.Header, name string) []string {
+	switch name {
+	case "Forwarded":
+		return parseForwarded(header.Values("Forwarded"))
+	case "X-Forwarded-For":
+		hops := []string{}
+		for _, value := range header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(value, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+		return hops
+	case "X-Real-Ip":
+		if value := header.Get("X-Real-IP"); value != "" {
+			return []string{strings.TrimSpace(value)}
+		}
+	}
+	return nil
+}
+
+// parseForwarded extracts for= parameters of RFC 7239 Forwarded headers.
+func parseForwarded(values []string) []string {
+	hops := []string{}
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			node := ""
+			for _, pair := range strings.Split(element, ";") {
+				key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(key, "for") {
+					node = strings.Trim(strings.TrimSpace(value), `"`)
+				}
+			}
+			hops = append(hops, node)
+		}
+	}
+	return hops
+}
+
+// parseHop parses an address which may have a port, and IPv6 addresses may be enclosed in brackets.
+func parseHop(s string) (netip.Addr, error) {
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), nil
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.Unmap(), nil
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
 }
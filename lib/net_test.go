@@ -0,0 +1,166 @@
+package lib_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/lib"
+)
+
+func TestClientIPResolver_Resolve(t *testing.T) {
+	trustedProxies := []string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/32"}
+
+	cases := []struct {
+		name            string
+		forwardedHeader string
+		ignoreHeaders   bool
+		remoteAddr      string
+		header          http.Header
+		wantIP          string
+	}{
+		{
+			name:       "no forwarding headers",
+			remoteAddr: "198.51.100.1:30000",
+			wantIP:     "198.51.100.1",
+		},
+		{
+			name:       "headers from untrusted peer are ignored",
+			remoteAddr: "198.51.100.1:30000",
+			header:     http.Header{"X-Forwarded-For": {"203.0.113.1"}},
+			wantIP:     "198.51.100.1",
+		},
+		{
+			name:       "x-forwarded-for from trusted proxy",
+			remoteAddr: "10.0.0.1:30000",
+			header:     http.Header{"X-Forwarded-For": {"203.0.113.1"}},
+			wantIP:     "203.0.113.1",
+		},
+		{
+			name:       "spoofed x-forwarded-for entry is skipped",
+			remoteAddr: "10.0.0.1:30000",
+			header:     http.Header{"X-Forwarded-For": {"127.0.0.1, 203.0.113.1 , 10.0.0.2"}},
+			wantIP:     "203.0.113.1",
+		},
+		{
+			name:       "multiple x-forwarded-for headers",
+			remoteAddr: "10.0.0.1:30000",
+			header:     http.Header{"X-Forwarded-For": {"203.0.113.1", "192.0.2.1"}},
+			wantIP:     "203.0.113.1",
+		},
+		{
+			name:       "all hops are trusted",
+			remoteAddr: "10.0.0.1:30000",
+			header:     http.Header{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			wantIP:     "10.0.0.3",
+		},
+		{
+			name:       "invalid hop",
+			remoteAddr: "10.0.0.1:30000",
+			header:     http.Header{"X-Forwarded-For": {"203.0.113.1, garbage, 10.0.0.2"}},
+			wantIP:     "10.0.0.2",
+		},
+		{
+			name:            "x-real-ip",
+			forwardedHeader: "X-Real-IP",
+			remoteAddr:      "10.0.0.1:30000",
+			header:          http.Header{"X-Real-Ip": {"203.0.113.1"}},
+			wantIP:          "203.0.113.1",
+		},
+		{
+			name:            "forwarded",
+			forwardedHeader: "Forwarded",
+			remoteAddr:      "10.0.0.1:30000",
+			header: http.Header{
+				"Forwarded":       {`for=198.51.100.7;proto=https, for="[2001:db8::1]:4711";by=10.0.0.1`},
+				"X-Forwarded-For": {"203.0.113.1"},
+			},
+			wantIP: "198.51.100.7",
+		},
+		{
+			name:       "spoofed forwarded is ignored when x-forwarded-for is configured",
+			remoteAddr: "10.0.0.1:30000",
+			header: http.Header{
+				"Forwarded":       {"for=192.0.2.55"},
+				"X-Forwarded-For": {"203.0.113.9"},
+			},
+			wantIP: "203.0.113.9",
+		},
+		{
+			name:            "spoofed x-forwarded-for is ignored when forwarded is configured",
+			forwardedHeader: "forwarded",
+			remoteAddr:      "10.0.0.1:30000",
+			header: http.Header{
+				"Forwarded":       {"for=203.0.113.9"},
+				"X-Forwarded-For": {"192.0.2.55"},
+			},
+			wantIP: "203.0.113.9",
+		},
+		{
+			name:       "configured header is missing",
+			remoteAddr: "10.0.0.1:30000",
+			header:     http.Header{"X-Real-Ip": {"203.0.113.1"}},
+			wantIP:     "10.0.0.1",
+		},
+		{
+			name:            "obfuscated forwarded node",
+			forwardedHeader: "Forwarded",
+			remoteAddr:      "10.0.0.1:30000",
+			header:          http.Header{"Forwarded": {`for=_hidden, for=10.0.0.2`}},
+			wantIP:          "10.0.0.2",
+		},
+		{
+			name:          "headers are ignored",
+			ignoreHeaders: true,
+			remoteAddr:    "10.0.0.1:30000",
+			header:        http.Header{"X-Forwarded-For": {"203.0.113.1"}},
+			wantIP:        "10.0.0.1",
+		},
+		{
+			name:       "ipv6 peer",
+			remoteAddr: "[2001:db8::2]:30000",
+			header:     http.Header{"X-Forwarded-For": {"2001:db9::1"}},
+			wantIP:     "2001:db9::1",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			forwardedHeader := tt.forwardedHeader
+			if forwardedHeader == "" {
+				forwardedHeader = lib.DefaultForwardedHeader
+			}
+			resolver, err := lib.NewClientIPResolver(trustedProxies, forwardedHeader, tt.ignoreHeaders)
+			if err != nil {
+				t.Fatalf("create resolver: %v", err)
+			}
+			req, err := http.NewRequest("GET", "/", nil)
+			if err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+			req.RemoteAddr = tt.remoteAddr
+			if tt.header != nil {
+				req.Header = tt.header
+			}
+
+			gotIP, err := resolver.Resolve(req)
+			if err != nil {
+				t.Fatalf("resolve: %v", err)
+			}
+			if tt.wantIP != gotIP {
+				t.Errorf("unexpected ip: want %s, but got %s", tt.wantIP, gotIP)
+			}
+		})
+	}
+}
+
+func TestNewClientIPResolver_InvalidProxy(t *testing.T) {
+	if _, err := lib.NewClientIPResolver([]string{"10.0.0.0/33"}, lib.DefaultForwardedHeader, false); err == nil {
+		t.Errorf("expected error, but got nil")
+	}
+}
+
+func TestNewClientIPResolver_UnsupportedHeader(t *testing.T) {
+	if _, err := lib.NewClientIPResolver(lib.DefaultTrustedProxies, "X-Client-IP", false); err == nil {
+		t.Errorf("expected error, but got nil")
+	}
+}
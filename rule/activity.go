@@ -0,0 +1,395 @@
+package rule
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Activity is a normalized form of an ActivityPub activity.
+// Properties which may be either an IRI, an embedded object or an array of them are decoded tolerantly.
+type Activity struct {
+	ID     string
+	Type   string
+	Types  []string
+	Actor  string
+	To     []string
+	Cc     []string
+	Object *ActivityObject
+}
+
+type ActivityObject struct {
+	ID         string
+	Type       string
+	Types      []string
+	Content    string
+	ContentMap map[string]string
+	Summary    string
+	Name       string
+	To         []string
+	Cc         []string
+	Tag        []ActivityTag
+	Attachment []ActivityAttachment
+	// PollOptions holds the names of oneOf and anyOf of a Question.
+	PollOptions []string
+}
+
+type ActivityTag struct {
+	Type string
+	HRef string
+	Name string
+}
+
+type ActivityAttachment struct {
+	Type      string
+	MediaType string
+	URL       string
+	Name      string
+	Blurhash  string
+	Width     int
+	Height    int
+}
+
+func ParseActivity(body []byte) (*Activity, error) {
+	activity := &Activity{}
+	if err := json.Unmarshal(body, activity); err != nil {
+		return nil, fmt.Errorf("unmarshal json: %w", err)
+	}
+	return activity, nil
+}
+
+func (a *Activity) HasType(t string) bool {
+	return slices.Contains(a.Types, t)
+}
+
+func (o *ActivityObject) HasType(t string) bool {
+	return slices.Contains(o.Types, t)
+}
+
+func (a *Activity) UnmarshalJSON(data []byte) error {
+	raw := struct {
+		ID     json.RawMessage `json:"id"`
+		Type   json.RawMessage `json:"type"`
+		Actor  json.RawMessage `json:"actor"`
+		To     json.RawMessage `json:"to"`
+		Cc     json.RawMessage `json:"cc"`
+		Object json.RawMessage `json:"object"`
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	activity := Activity{}
+	var err error
+	if activity.ID, err = decodeReference(raw.ID); err != nil {
+		return fmt.Errorf("decode id: %w", err)
+	}
+	if activity.Types, err = decodeReferences(raw.Type); err != nil {
+		return fmt.Errorf("decode type: %w", err)
+	}
+	activity.Type = firstOrEmpty(activity.Types)
+	if activity.Actor, err = decodeReference(raw.Actor); err != nil {
+		return fmt.Errorf("decode actor: %w", err)
+	}
+	if activity.To, err = decodeReferences(raw.To); err != nil {
+		return fmt.Errorf("decode to: %w", err)
+	}
+	if activity.Cc, err = decodeReferences(raw.Cc); err != nil {
+		return fmt.Errorf("decode cc: %w", err)
+	}
+	objects, err := decodeList[ActivityObject](raw.Object)
+	if err != nil {
+		return fmt.Errorf("decode object: %w", err)
+	}
+	if len(objects) > 0 {
+		activity.Object = &objects[0]
+	}
+
+	*a = activity
+	return nil
+}
+
+func (o *ActivityObject) UnmarshalJSON(data []byte) error {
+	// object may be referenced by its IRI instead of being embedded
+	if id, ok := decodeString(data); ok {
+		*o = ActivityObject{ID: id}
+		return nil
+	}
+
+	raw := struct {
+		ID         json.RawMessage `json:"id"`
+		Type       json.RawMessage `json:"type"`
+		Content    json.RawMessage `json:"content"`
+		ContentMap json.RawMessage `json:"contentMap"`
+		Summary    json.RawMessage `json:"summary"`
+		Name       json.RawMessage `json:"name"`
+		To         json.RawMessage `json:"to"`
+		Cc         json.RawMessage `json:"cc"`
+		Tag        json.RawMessage `json:"tag"`
+		Attachment json.RawMessage `json:"attachment"`
+		OneOf      json.RawMessage `json:"oneOf"`
+		AnyOf      json.RawMessage `json:"anyOf"`
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	object := ActivityObject{}
+	var err error
+	if object.ID, err = decodeReference(raw.ID); err != nil {
+		return fmt.Errorf("decode id: %w", err)
+	}
+	if object.Types, err = decodeReferences(raw.Type); err != nil {
+		return fmt.Errorf("decode type: %w", err)
+	}
+	object.Type = firstOrEmpty(object.Types)
+	object.Content, _ = decodeString(raw.Content)
+	object.ContentMap = decodeLanguageMap(raw.ContentMap)
+	object.Summary, _ = decodeString(raw.Summary)
+	object.Name, _ = decodeString(raw.Name)
+	if object.To, err = decodeReferences(raw.To); err != nil {
+		return fmt.Errorf("decode to: %w", err)
+	}
+	if object.Cc, err = decodeReferences(raw.Cc); err != nil {
+		return fmt.Errorf("decode cc: %w", err)
+	}
+	if object.Tag, err = decodeList[ActivityTag](raw.Tag); err != nil {
+		return fmt.Errorf("decode tag: %w", err)
+	}
+	if object.Attachment, err = decodeList[ActivityAttachment](raw.Attachment); err != nil {
+		return fmt.Errorf("decode attachment: %w", err)
+	}
+	for _, choices := range []json.RawMessage{raw.OneOf, raw.AnyOf} {
+		options, err := decodeList[ActivityObject](choices)
+		if err != nil {
+			return fmt.Errorf("decode poll options: %w", err)
+		}
+		for _, option := range options {
+			if option.Name != "" {
+				object.PollOptions = append(object.PollOptions, option.Name)
+			}
+		}
+	}
+
+	*o = object
+	return nil
+}
+
+func (t *ActivityTag) UnmarshalJSON(data []byte) error {
+	if href, ok := decodeString(data); ok {
+		*t = ActivityTag{HRef: href}
+		return nil
+	}
+
+	raw := struct {
+		Type json.RawMessage `json:"type"`
+		HRef json.RawMessage `json:"href"`
+		Name json.RawMessage `json:"name"`
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	types, err := decodeReferences(raw.Type)
+	if err != nil {
+		return fmt.Errorf("decode type: %w", err)
+	}
+	href, err := decodeReference(raw.HRef)
+	if err != nil {
+		return fmt.Errorf("decode href: %w", err)
+	}
+	name, _ := decodeString(raw.Name)
+	*t = ActivityTag{
+		Type: firstOrEmpty(types),
+		HRef: href,
+		Name: name,
+	}
+	return nil
+}
+
+func (a *ActivityAttachment) UnmarshalJSON(data []byte) error {
+	if url, ok := decodeString(data); ok {
+		*a = ActivityAttachment{URL: url}
+		return nil
+	}
+
+	raw := struct {
+		Type      json.RawMessage `json:"type"`
+		MediaType json.RawMessage `json:"mediaType"`
+		URL       json.RawMessage `json:"url"`
+		Name      json.RawMessage `json:"name"`
+		Blurhash  json.RawMessage `json:"blurhash"`
+		Width     json.RawMessage `json:"width"`
+		Height    json.RawMessage `json:"height"`
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	types, err := decodeReferences(raw.Type)
+	if err != nil {
+		return fmt.Errorf("decode type: %w", err)
+	}
+	mediaType, _ := decodeString(raw.MediaType)
+	name, _ := decodeString(raw.Name)
+	// url may be a Link object or an array of them
+	links, err := decodeList[activityLink](raw.URL)
+	if err != nil {
+		return fmt.Errorf("decode url: %w", err)
+	}
+	blurhash, _ := decodeString(raw.Blurhash)
+	attachment := ActivityAttachment{
+		Type:      firstOrEmpty(types),
+		MediaType: mediaType,
+		Name:      name,
+		Blurhash:  blurhash,
+		Width:     decodeInt(raw.Width),
+		Height:    decodeInt(raw.Height),
+	}
+	if len(links) > 0 {
+		attachment.URL = links[0].HRef
+		if attachment.MediaType == "" {
+			attachment.MediaType = links[0].MediaType
+		}
+	}
+	*a = attachment
+	return nil
+}
+
+type activityLink struct {
+	HRef      string
+	MediaType string
+}
+
+func (l *activityLink) UnmarshalJSON(data []byte) error {
+	if href, ok := decodeString(data); ok {
+		*l = activityLink{HRef: href}
+		return nil
+	}
+
+	raw := struct {
+		HRef      json.RawMessage `json:"href"`
+		MediaType json.RawMessage `json:"mediaType"`
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	href, err := decodeReference(raw.HRef)
+	if err != nil {
+		return fmt.Errorf("decode href: %w", err)
+	}
+	mediaType, _ := decodeString(raw.MediaType)
+	*l = activityLink{HRef: href, MediaType: mediaType}
+	return nil
+}
+
+// decodeReference decodes an IRI given as a string, an object with id or an array of them.
+// The first reference is returned when multiple references are given.
+func decodeReference(data json.RawMessage) (string, error) {
+	refs, err := decodeReferences(data)
+	if err != nil {
+		return "", err
+	}
+	return firstOrEmpty(refs), nil
+}
+
+func decodeReferences(data json.RawMessage) ([]string, error) {
+	refs, err := decodeList[reference](data)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, ref := range refs {
+		if ref != "" {
+			result = append(result, string(ref))
+		}
+	}
+	return result, nil
+}
+
+type reference string
+
+func (r *reference) UnmarshalJSON(data []byte) error {
+	if s, ok := decodeString(data); ok {
+		*r = reference(s)
+		return nil
+	}
+	object := struct {
+		ID   json.RawMessage `json:"id"`
+		HRef json.RawMessage `json:"href"`
+	}{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	if id, ok := decodeString(object.ID); ok {
+		*r = reference(id)
+		return nil
+	}
+	href, _ := decodeString(object.HRef)
+	*r = reference(href)
+	return nil
+}
+
+// decodeList decodes either a single value or an array of values.
+func decodeList[T any](data json.RawMessage) ([]T, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	if data[0] == '[' {
+		items := []T{}
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+	var item T
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, err
+	}
+	return []T{item}, nil
+}
+
+// decodeInt decodes a number, returning 0 for other values.
+func decodeInt(data json.RawMessage) int {
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return 0
+	}
+	return int(f)
+}
+
+// decodeLanguageMap decodes a natural language map such as contentMap, ignoring malformed values.
+func decodeLanguageMap(data json.RawMessage) map[string]string {
+	values := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &values); err != nil || len(values) == 0 {
+		return nil
+	}
+	languageMap := map[string]string{}
+	for lang, value := range values {
+		if s, ok := decodeString(value); ok {
+			languageMap[lang] = s
+		}
+	}
+	return languageMap
+}
+
+func decodeString(data json.RawMessage) (string, bool) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '"' {
+		return "", false
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+func firstOrEmpty(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	return items[0]
+}
@@ -0,0 +1,460 @@
+package rule_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestParseActivity(t *testing.T) {
+	mastodonCreateBody := `
+	{
+		"@context": [
+			"https://www.w3.org/ns/activitystreams",
+			{
+				"ostatus": "http://ostatus.org#",
+				"sensitive": "as:sensitive",
+				"toot": "http://joinmastodon.org/ns#"
+			}
+		],
+		"id": "https://mastodon.example/users/alice/statuses/111950688941481832/activity",
+		"type": "Create",
+		"actor": "https://mastodon.example/users/alice",
+		"published": "2024-02-18T04:52:27Z",
+		"to": ["https://www.w3.org/ns/activitystreams#Public"],
+		"cc": [
+			"https://mastodon.example/users/alice/followers",
+			"https://misskey.example/users/9abcdef"
+		],
+		"object": {
+			"id": "https://mastodon.example/users/alice/statuses/111950688941481832",
+			"type": "Note",
+			"summary": null,
+			"inReplyTo": null,
+			"published": "2024-02-18T04:52:27Z",
+			"url": "https://mastodon.example/@alice/111950688941481832",
+			"attributedTo": "https://mastodon.example/users/alice",
+			"to": ["https://www.w3.org/ns/activitystreams#Public"],
+			"cc": [
+				"https://mastodon.example/users/alice/followers",
+				"https://misskey.example/users/9abcdef"
+			],
+			"sensitive": false,
+			"content": "<p><span class=\"h-card\"><a href=\"https://misskey.example/@bob\" class=\"u-url mention\">@<span>bob</span></a></span> hello</p>",
+			"attachment": [
+				{
+					"type": "Document",
+					"mediaType": "image/png",
+					"url": "https://mastodon.example/system/media_attachments/files/000/000/001/original/image.png",
+					"name": "alt text",
+					"blurhash": "UBL_:rOpGG-oBUNG,qRj2so|=eE1w^n4S5NH",
+					"width": 640,
+					"height": 480
+				}
+			],
+			"tag": [
+				{
+					"type": "Mention",
+					"href": "https://misskey.example/users/9abcdef",
+					"name": "@bob@misskey.example"
+				},
+				{
+					"type": "Hashtag",
+					"href": "https://mastodon.example/tags/test",
+					"name": "#test"
+				}
+			]
+		}
+	}`
+	misskeyCreateBody := `
+	{
+		"@context": [
+			"https://www.w3.org/ns/activitystreams",
+			"https://w3id.org/security/v1",
+			{"Key": "sec:Key", "_misskey_content": "misskey:_misskey_content", "misskey": "https://misskey-hub.net/ns#"}
+		],
+		"id": "https://misskey.example/notes/9q3nz5ld8v/activity",
+		"actor": "https://misskey.example/users/9abcdef",
+		"type": "Create",
+		"published": "2024-02-18T04:52:27.000Z",
+		"object": {
+			"id": "https://misskey.example/notes/9q3nz5ld8v",
+			"type": "Note",
+			"attributedTo": "https://misskey.example/users/9abcdef",
+			"content": "<p><a href=\"https://mastodon.example/@alice\" class=\"u-url mention\">@alice@mastodon.example</a> :blobcat:</p>",
+			"_misskey_content": "@alice@mastodon.example :blobcat:",
+			"source": {"content": "@alice@mastodon.example :blobcat:", "mediaType": "text/x.misskeymarkdown"},
+			"published": "2024-02-18T04:52:27.000Z",
+			"to": ["https://www.w3.org/ns/activitystreams#Public"],
+			"cc": ["https://misskey.example/users/9abcdef/followers", "https://mastodon.example/users/alice"],
+			"inReplyTo": null,
+			"attachment": [],
+			"sensitive": false,
+			"tag": [
+				{"type": "Mention", "href": "https://mastodon.example/users/alice", "name": "@alice@mastodon.example"},
+				{
+					"id": "https://misskey.example/emojis/blobcat",
+					"type": "Emoji",
+					"name": ":blobcat:",
+					"updated": "2024-01-01T00:00:00.000Z",
+					"icon": {"type": "Image", "mediaType": "image/png", "url": "https://misskey.example/files/blobcat.png"}
+				}
+			]
+		},
+		"to": ["https://www.w3.org/ns/activitystreams#Public"],
+		"cc": ["https://misskey.example/users/9abcdef/followers", "https://mastodon.example/users/alice"]
+	}`
+	pleromaCreateBody := `
+	{
+		"@context": ["https://www.w3.org/ns/activitystreams", "https://pleroma.example/schemas/litepub-0.1.jsonld", {"@language": "und"}],
+		"actor": "https://pleroma.example/users/carol",
+		"cc": ["https://pleroma.example/users/carol/followers"],
+		"context": "https://pleroma.example/contexts/0d5f7a3e",
+		"directMessage": false,
+		"id": "https://pleroma.example/activities/3d8a1f4c",
+		"object": {
+			"actor": "https://pleroma.example/users/carol",
+			"attachment": [
+				{
+					"mediaType": "image/jpeg",
+					"name": "",
+					"type": "Document",
+					"url": [
+						{"href": "https://pleroma.example/media/photo.jpg", "mediaType": "image/jpeg", "type": "Link"}
+					]
+				}
+			],
+			"attributedTo": "https://pleroma.example/users/carol",
+			"cc": ["https://pleroma.example/users/carol/followers"],
+			"content": "look at this",
+			"id": "https://pleroma.example/objects/7e2c9b10",
+			"published": "2024-02-18T04:52:27.000000Z",
+			"sensitive": false,
+			"summary": "",
+			"tag": [],
+			"to": ["https://www.w3.org/ns/activitystreams#Public"],
+			"type": "Note"
+		},
+		"published": "2024-02-18T04:52:27.000000Z",
+		"to": ["https://www.w3.org/ns/activitystreams#Public"],
+		"type": "Create"
+	}`
+	goToSocialCreateBody := `
+	{
+		"@context": "https://www.w3.org/ns/activitystreams",
+		"actor": "https://gts.example/users/dave",
+		"cc": "https://gts.example/users/dave/followers",
+		"id": "https://gts.example/users/dave/statuses/01HPZ6Q5K1/activity",
+		"object": {
+			"attachment": {
+				"blurhash": "LNJRyVof~qj[ayayj[j[%Mj[WBay",
+				"mediaType": "image/webp",
+				"name": "a cat",
+				"type": "Image",
+				"url": "https://gts.example/fileserver/01HPZ/attachment/original/01HPZ.webp"
+			},
+			"attributedTo": "https://gts.example/users/dave",
+			"cc": "https://gts.example/users/dave/followers",
+			"content": "<p>hi <span class=\"h-card\"><a href=\"https://mastodon.example/@alice\" class=\"u-url mention\">@<span>alice</span></a></span></p>",
+			"id": "https://gts.example/users/dave/statuses/01HPZ6Q5K1",
+			"published": "2024-02-18T04:52:27Z",
+			"tag": {
+				"href": "https://mastodon.example/users/alice",
+				"name": "@alice@mastodon.example",
+				"type": "Mention"
+			},
+			"to": "https://www.w3.org/ns/activitystreams#Public",
+			"type": "Note"
+		},
+		"published": "2024-02-18T04:52:27Z",
+		"to": "https://www.w3.org/ns/activitystreams#Public",
+		"type": "Create"
+	}`
+	embeddedActorBody := `
+	{
+		"id": "https://example.com/activities/1",
+		"type": ["Create", "as:Create"],
+		"actor": {"id": "https://example.com/users/eve", "type": "Person"},
+		"object": {"id": "https://example.com/objects/1", "type": ["Note"], "content": "hello"}
+	}`
+	mastodonDeleteActorBody := `
+	{
+		"@context": "https://www.w3.org/ns/activitystreams",
+		"id": "https://mastodon.example/users/alice#delete",
+		"type": "Delete",
+		"actor": "https://mastodon.example/users/alice",
+		"to": ["https://www.w3.org/ns/activitystreams#Public"],
+		"object": "https://mastodon.example/users/alice",
+		"signature": {"type": "RsaSignature2017", "creator": "https://mastodon.example/users/alice#main-key", "signatureValue": "..."}
+	}`
+	misskeyLikeBody := `
+	{
+		"@context": ["https://www.w3.org/ns/activitystreams"],
+		"type": "Like",
+		"id": "https://misskey.example/likes/9q3o0a1b2c",
+		"actor": "https://misskey.example/users/9abcdef",
+		"object": "https://mastodon.example/users/alice/statuses/111950688941481832",
+		"content": ":blobcat:",
+		"_misskey_reaction": ":blobcat:",
+		"tag": [{"id": "https://misskey.example/emojis/blobcat", "type": "Emoji", "name": ":blobcat:"}]
+	}`
+
+	mastodonQuestionBody := `
+	{
+		"id": "https://mastodon.example/users/alice/statuses/111950688941481833/activity",
+		"type": "Create",
+		"actor": "https://mastodon.example/users/alice",
+		"object": {
+			"id": "https://mastodon.example/users/alice/statuses/111950688941481833",
+			"type": "Question",
+			"summary": "content warning",
+			"content": "<p>which one?</p>",
+			"contentMap": {"en": "<p>which one?</p>", "ja": "<p>どれ?</p>", "invalid": 1},
+			"endTime": "2024-02-19T04:52:27Z",
+			"oneOf": [
+				{"type": "Note", "name": "first", "replies": {"type": "Collection", "totalItems": 0}},
+				{"type": "Note", "name": "second", "replies": {"type": "Collection", "totalItems": 0}}
+			],
+			"attachment": []
+		}
+	}`
+
+	cases := []struct {
+		name         string
+		body         string
+		wantActivity *rule.Activity
+	}{
+		{
+			name: "Mastodon Create Note",
+			body: mastodonCreateBody,
+			wantActivity: &rule.Activity{
+				ID:    "https://mastodon.example/users/alice/statuses/111950688941481832/activity",
+				Type:  "Create",
+				Types: []string{"Create"},
+				Actor: "https://mastodon.example/users/alice",
+				To:    []string{"https://www.w3.org/ns/activitystreams#Public"},
+				Cc:    []string{"https://mastodon.example/users/alice/followers", "https://misskey.example/users/9abcdef"},
+				Object: &rule.ActivityObject{
+					ID:      "https://mastodon.example/users/alice/statuses/111950688941481832",
+					Type:    "Note",
+					Types:   []string{"Note"},
+					Content: "<p><span class=\"h-card\"><a href=\"https://misskey.example/@bob\" class=\"u-url mention\">@<span>bob</span></a></span> hello</p>",
+					To:      []string{"https://www.w3.org/ns/activitystreams#Public"},
+					Cc:      []string{"https://mastodon.example/users/alice/followers", "https://misskey.example/users/9abcdef"},
+					Tag: []rule.ActivityTag{
+						{Type: "Mention", HRef: "https://misskey.example/users/9abcdef", Name: "@bob@misskey.example"},
+						{Type: "Hashtag", HRef: "https://mastodon.example/tags/test", Name: "#test"},
+					},
+					Attachment: []rule.ActivityAttachment{
+						{
+							Type:      "Document",
+							MediaType: "image/png",
+							URL:       "https://mastodon.example/system/media_attachments/files/000/000/001/original/image.png",
+							Name:      "alt text",
+							Blurhash:  "UBL_:rOpGG-oBUNG,qRj2so|=eE1w^n4S5NH",
+							Width:     640,
+							Height:    480,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Misskey Create Note",
+			body: misskeyCreateBody,
+			wantActivity: &rule.Activity{
+				ID:    "https://misskey.example/notes/9q3nz5ld8v/activity",
+				Type:  "Create",
+				Types: []string{"Create"},
+				Actor: "https://misskey.example/users/9abcdef",
+				To:    []string{"https://www.w3.org/ns/activitystreams#Public"},
+				Cc:    []string{"https://misskey.example/users/9abcdef/followers", "https://mastodon.example/users/alice"},
+				Object: &rule.ActivityObject{
+					ID:      "https://misskey.example/notes/9q3nz5ld8v",
+					Type:    "Note",
+					Types:   []string{"Note"},
+					Content: "<p><a href=\"https://mastodon.example/@alice\" class=\"u-url mention\">@alice@mastodon.example</a> :blobcat:</p>",
+					To:      []string{"https://www.w3.org/ns/activitystreams#Public"},
+					Cc:      []string{"https://misskey.example/users/9abcdef/followers", "https://mastodon.example/users/alice"},
+					Tag: []rule.ActivityTag{
+						{Type: "Mention", HRef: "https://mastodon.example/users/alice", Name: "@alice@mastodon.example"},
+						{Type: "Emoji", Name: ":blobcat:"},
+					},
+					Attachment: []rule.ActivityAttachment{},
+				},
+			},
+		},
+		{
+			name: "Pleroma Create Note with Link url",
+			body: pleromaCreateBody,
+			wantActivity: &rule.Activity{
+				ID:    "https://pleroma.example/activities/3d8a1f4c",
+				Type:  "Create",
+				Types: []string{"Create"},
+				Actor: "https://pleroma.example/users/carol",
+				To:    []string{"https://www.w3.org/ns/activitystreams#Public"},
+				Cc:    []string{"https://pleroma.example/users/carol/followers"},
+				Object: &rule.ActivityObject{
+					ID:      "https://pleroma.example/objects/7e2c9b10",
+					Type:    "Note",
+					Types:   []string{"Note"},
+					Content: "look at this",
+					To:      []string{"https://www.w3.org/ns/activitystreams#Public"},
+					Cc:      []string{"https://pleroma.example/users/carol/followers"},
+					Tag:     []rule.ActivityTag{},
+					Attachment: []rule.ActivityAttachment{
+						{Type: "Document", MediaType: "image/jpeg", URL: "https://pleroma.example/media/photo.jpg"},
+					},
+				},
+			},
+		},
+		{
+			name: "GoToSocial Create Note with single values",
+			body: goToSocialCreateBody,
+			wantActivity: &rule.Activity{
+				ID:    "https://gts.example/users/dave/statuses/01HPZ6Q5K1/activity",
+				Type:  "Create",
+				Types: []string{"Create"},
+				Actor: "https://gts.example/users/dave",
+				To:    []string{"https://www.w3.org/ns/activitystreams#Public"},
+				Cc:    []string{"https://gts.example/users/dave/followers"},
+				Object: &rule.ActivityObject{
+					ID:      "https://gts.example/users/dave/statuses/01HPZ6Q5K1",
+					Type:    "Note",
+					Types:   []string{"Note"},
+					Content: "<p>hi <span class=\"h-card\"><a href=\"https://mastodon.example/@alice\" class=\"u-url mention\">@<span>alice</span></a></span></p>",
+					To:      []string{"https://www.w3.org/ns/activitystreams#Public"},
+					Cc:      []string{"https://gts.example/users/dave/followers"},
+					Tag: []rule.ActivityTag{
+						{Type: "Mention", HRef: "https://mastodon.example/users/alice", Name: "@alice@mastodon.example"},
+					},
+					Attachment: []rule.ActivityAttachment{
+						{
+							Type:      "Image",
+							MediaType: "image/webp",
+							URL:       "https://gts.example/fileserver/01HPZ/attachment/original/01HPZ.webp",
+							Name:      "a cat",
+							Blurhash:  "LNJRyVof~qj[ayayj[j[%Mj[WBay",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "embedded actor and array types",
+			body: embeddedActorBody,
+			wantActivity: &rule.Activity{
+				ID:    "https://example.com/activities/1",
+				Type:  "Create",
+				Types: []string{"Create", "as:Create"},
+				Actor: "https://example.com/users/eve",
+				Object: &rule.ActivityObject{
+					ID:      "https://example.com/objects/1",
+					Type:    "Note",
+					Types:   []string{"Note"},
+					Content: "hello",
+				},
+			},
+		},
+		{
+			name: "Mastodon Delete actor with IRI object",
+			body: mastodonDeleteActorBody,
+			wantActivity: &rule.Activity{
+				ID:     "https://mastodon.example/users/alice#delete",
+				Type:   "Delete",
+				Types:  []string{"Delete"},
+				Actor:  "https://mastodon.example/users/alice",
+				To:     []string{"https://www.w3.org/ns/activitystreams#Public"},
+				Object: &rule.ActivityObject{ID: "https://mastodon.example/users/alice"},
+			},
+		},
+		{
+			name: "Mastodon Create Question",
+			body: mastodonQuestionBody,
+			wantActivity: &rule.Activity{
+				ID:    "https://mastodon.example/users/alice/statuses/111950688941481833/activity",
+				Type:  "Create",
+				Types: []string{"Create"},
+				Actor: "https://mastodon.example/users/alice",
+				Object: &rule.ActivityObject{
+					ID:          "https://mastodon.example/users/alice/statuses/111950688941481833",
+					Type:        "Question",
+					Types:       []string{"Question"},
+					Content:     "<p>which one?</p>",
+					ContentMap:  map[string]string{"en": "<p>which one?</p>", "ja": "<p>どれ?</p>"},
+					Summary:     "content warning",
+					Attachment:  []rule.ActivityAttachment{},
+					PollOptions: []string{"first", "second"},
+				},
+			},
+		},
+		{
+			name: "Misskey Like with IRI object",
+			body: misskeyLikeBody,
+			wantActivity: &rule.Activity{
+				ID:     "https://misskey.example/likes/9q3o0a1b2c",
+				Type:   "Like",
+				Types:  []string{"Like"},
+				Actor:  "https://misskey.example/users/9abcdef",
+				Object: &rule.ActivityObject{ID: "https://mastodon.example/users/alice/statuses/111950688941481832"},
+			},
+		},
+		{
+			name: "scalar addressing and url array of strings",
+			body: `{
+				"type": "Create",
+				"actor": "https://example.com/users/frank",
+				"to": "https://example.net/users/alice",
+				"object": {
+					"type": "Note",
+					"content": "hi",
+					"to": "https://example.net/users/alice",
+					"cc": "https://example.com/users/frank/followers",
+					"attachment": [
+						{"type": "Document", "url": ["https://example.com/media/a.png", "https://example.com/media/a_small.png"]}
+					]
+				}
+			}`,
+			wantActivity: &rule.Activity{
+				Type:  "Create",
+				Types: []string{"Create"},
+				Actor: "https://example.com/users/frank",
+				To:    []string{"https://example.net/users/alice"},
+				Object: &rule.ActivityObject{
+					Type:    "Note",
+					Types:   []string{"Note"},
+					Content: "hi",
+					To:      []string{"https://example.net/users/alice"},
+					Cc:      []string{"https://example.com/users/frank/followers"},
+					Attachment: []rule.ActivityAttachment{
+						{Type: "Document", URL: "https://example.com/media/a.png"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			gotActivity, err := rule.ParseActivity([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("parse activity: %v", err)
+			}
+			if !reflect.DeepEqual(tt.wantActivity, gotActivity) {
+				t.Errorf("unexpected activity:\nwant %+v\n got %+v", tt.wantActivity, gotActivity)
+				if tt.wantActivity.Object != nil && gotActivity.Object != nil {
+					t.Errorf("unexpected object:\nwant %+v\n got %+v", *tt.wantActivity.Object, *gotActivity.Object)
+				}
+			}
+		})
+	}
+}
+
+func TestParseActivity_InvalidBody(t *testing.T) {
+	for _, body := range []string{``, `[]`, `"text"`, `{"type": 1}`} {
+		if _, err := rule.ParseActivity([]byte(body)); err == nil {
+			t.Errorf("expected error for %q, but got nil", body)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package rule
+
+import (
+	"fmt"
+	"strings"
+)
+
+// postedActivityTypes are activities which post or edit an object.
+var postedActivityTypes = []string{"Create", "Update"}
+
+type activityTypeMatcher struct {
+	types []string
+}
+
+func NewActivityTypeMatcher(types []string) (*activityTypeMatcher, error) {
+	if len(types) == 0 {
+		return nil, fmt.Errorf("empty types")
+	}
+	return &activityTypeMatcher{
+		types: types,
+	}, nil
+}
+
+func (m *activityTypeMatcher) Test(req *ProxyRequest) (bool, error) {
+	if !strings.HasSuffix(req.Request.URL.Path, "/inbox") {
+		return false, nil
+	}
+
+	activity, err := req.Activity()
+	if err != nil {
+		return false, fmt.Errorf("parse activity: %w", err)
+	}
+
+	for _, t := range m.types {
+		if activity.HasType(t) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type objectTypeMatcher struct {
+	types []string
+}
+
+func NewObjectTypeMatcher(types []string) (*objectTypeMatcher, error) {
+	if len(types) == 0 {
+		return nil, fmt.Errorf("empty types")
+	}
+	return &objectTypeMatcher{
+		types: types,
+	}, nil
+}
+
+func (m *objectTypeMatcher) Test(req *ProxyRequest) (bool, error) {
+	if !strings.HasSuffix(req.Request.URL.Path, "/inbox") {
+		return false, nil
+	}
+
+	activity, err := req.Activity()
+	if err != nil {
+		return false, fmt.Errorf("parse activity: %w", err)
+	}
+
+	if activity.Object == nil {
+		return false, nil
+	}
+	for _, t := range m.types {
+		if activity.Object.HasType(t) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// postedObject returns the object created or updated by the activity delivered to an inbox,
+// or nil for other requests.
+func postedObject(req *ProxyRequest) (*ActivityObject, error) {
+	if !strings.HasSuffix(req.Request.URL.Path, "/inbox") {
+		return nil, nil
+	}
+
+	activity, err := req.Activity()
+	if err != nil {
+		return nil, fmt.Errorf("parse activity: %w", err)
+	}
+
+	if activity.Object == nil {
+		return nil, nil
+	}
+	for _, t := range postedActivityTypes {
+		if activity.HasType(t) {
+			return activity.Object, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,118 @@
+package rule_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestActivityTypeMatcher_Test(t *testing.T) {
+	cases := []struct {
+		name       string
+		path       string
+		body       string
+		types      []string
+		wantResult bool
+	}{
+		{
+			name:       "activity type is listed",
+			path:       "/inbox",
+			body:       `{"type": "Update", "actor": "https://example.com/users/bob", "object": {"type": "Note"}}`,
+			types:      []string{"Create", "Update"},
+			wantResult: true,
+		},
+		{
+			name:       "one of activity types is listed",
+			path:       "/inbox",
+			body:       `{"type": ["Flag", "Object"], "actor": "https://example.com/users/bob", "object": "https://example.net/users/alice"}`,
+			types:      []string{"Flag"},
+			wantResult: true,
+		},
+		{
+			name:       "activity type is not listed",
+			path:       "/inbox",
+			body:       `{"type": "Like", "actor": "https://example.com/users/bob", "object": "https://example.net/notes/1"}`,
+			types:      []string{"EmojiReact"},
+			wantResult: false,
+		},
+		{
+			name:       "request other than inbox",
+			path:       "/api/v1/statuses",
+			body:       `{"type": "Create", "actor": "https://example.com/users/bob"}`,
+			types:      []string{"Create"},
+			wantResult: false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := rule.NewActivityTypeMatcher(tt.types)
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			req, err := http.NewRequest("POST", tt.path, bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+
+			got, err := m.Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestObjectTypeMatcher_Test(t *testing.T) {
+	cases := []struct {
+		name       string
+		body       string
+		types      []string
+		wantResult bool
+	}{
+		{
+			name:       "object type is listed",
+			body:       `{"type": "Create", "actor": "https://example.com/users/bob", "object": {"type": "Question"}}`,
+			types:      []string{"Question", "Article"},
+			wantResult: true,
+		},
+		{
+			name:       "object type is not listed",
+			body:       `{"type": "Create", "actor": "https://example.com/users/bob", "object": {"type": "Note"}}`,
+			types:      []string{"Question", "Article"},
+			wantResult: false,
+		},
+		{
+			name:       "object is referenced by IRI",
+			body:       `{"type": "Announce", "actor": "https://example.com/users/bob", "object": "https://example.net/notes/1"}`,
+			types:      []string{"Note"},
+			wantResult: false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := rule.NewObjectTypeMatcher(tt.types)
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			req, err := http.NewRequest("POST", "/inbox", bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+
+			got, err := m.Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package rule
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type actorDomainMatcher struct {
+	domains *DomainSet
+}
+
+func NewActorDomainMatcher(domains *DomainSet) (*actorDomainMatcher, error) {
+	if domains == nil {
+		return nil, fmt.Errorf("nil domain set")
+	}
+	return &actorDomainMatcher{
+		domains: domains,
+	}, nil
+}
+
+func (m *actorDomainMatcher) Test(req *ProxyRequest) (bool, error) {
+	if !strings.HasSuffix(req.Request.URL.Path, "/inbox") {
+		return false, nil
+	}
+
+	activity, err := req.Activity()
+	if err != nil {
+		return false, fmt.Errorf("parse activity: %w", err)
+	}
+
+	actor, err := url.Parse(activity.Actor)
+	if err != nil {
+		return false, nil
+	}
+	return m.domains.Contains(actor.Hostname()), nil
+}
@@ -0,0 +1,75 @@
+package rule_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestActorDomainMatcher_Test(t *testing.T) {
+	domains, err := rule.NewDomainSet([]string{"blocked.example", "*.spam.example"})
+	if err != nil {
+		t.Fatalf("create domain set: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		path       string
+		actor      string
+		wantResult bool
+	}{
+		{
+			name:       "actor is on blocked domain",
+			path:       "/inbox",
+			actor:      "https://blocked.example/users/bob",
+			wantResult: true,
+		},
+		{
+			name:       "actor is on blocked subdomain",
+			path:       "/users/alice/inbox",
+			actor:      "https://a.spam.example/users/bob",
+			wantResult: true,
+		},
+		{
+			name:       "actor is not on blocked domain",
+			path:       "/inbox",
+			actor:      "https://example.com/users/bob",
+			wantResult: false,
+		},
+		{
+			name:       "request other than inbox",
+			path:       "/api/v1/statuses",
+			actor:      "https://blocked.example/users/bob",
+			wantResult: false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := rule.NewActorDomainMatcher(domains)
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+
+			body, err := json.Marshal(map[string]string{"type": "Follow", "actor": tt.actor, "object": "https://example.net/users/alice"})
+			if err != nil {
+				t.Fatalf("marshal json: %v", err)
+			}
+			req, err := http.NewRequest("POST", tt.path, bytes.NewBuffer(body))
+			if err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+
+			got, err := m.Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
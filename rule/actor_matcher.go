@@ -1,21 +1,28 @@
 package rule
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 )
 
 type actorMatcher struct {
-	prefix string
+	pattern StringPattern
 }
 
 func NewActorMatcher(prefix string) (*actorMatcher, error) {
-	if len(prefix) == 0 {
+	prefixPattern, err := NewPrefixPattern(prefix, false)
+	if err != nil {
 		return nil, fmt.Errorf("empty prefix pattern: %s", prefix)
 	}
+	return NewActorPatternMatcher(prefixPattern)
+}
+
+func NewActorPatternMatcher(pattern StringPattern) (*actorMatcher, error) {
+	if pattern == nil {
+		return nil, fmt.Errorf("nil pattern")
+	}
 	return &actorMatcher{
-		prefix: prefix,
+		pattern: pattern,
 	}, nil
 }
 
@@ -24,17 +31,10 @@ func (m *actorMatcher) Test(req *ProxyRequest) (bool, error) {
 		return false, nil
 	}
 
-	body, err := req.Body()
+	activity, err := req.Activity()
 	if err != nil {
-		return false, fmt.Errorf("read body: %w", err)
-	}
-
-	payload := struct {
-		Actor string `json:"actor"`
-	}{}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		return false, fmt.Errorf("unmarshal json: %w", err)
+		return false, fmt.Errorf("parse activity: %w", err)
 	}
 
-	return strings.HasPrefix(payload.Actor, m.prefix), nil
+	return m.pattern.Match(activity.Actor), nil
 }
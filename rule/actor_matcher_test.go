@@ -64,3 +64,50 @@ func TestActorMatcher_Test(t *testing.T) {
 		})
 	}
 }
+
+func TestActorMatcher_Test_ObjectShapes(t *testing.T) {
+	cases := []struct {
+		name   string
+		object any
+	}{
+		{
+			name:   "object is a reference",
+			object: "https://example.net/users/alice",
+		},
+		{
+			name:   "object is embedded",
+			object: map[string]any{"type": "Note", "content": "hello"},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := rule.NewActorMatcher("https://example.com")
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+
+			body, err := json.Marshal(map[string]any{
+				"type":   "Follow",
+				"actor":  "https://example.com/users/bob",
+				"object": tt.object,
+			})
+			if err != nil {
+				t.Fatalf("marshal json: %v", err)
+			}
+
+			req, err := http.NewRequest("POST", "/inbox", bytes.NewBuffer(body))
+			if err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+
+			gotResult, err := m.Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("test: %v", err)
+			}
+			if !gotResult {
+				t.Errorf("unexpected result: want %v, but got %v", true, gotResult)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package rule
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// nonMediaAttachmentTypes are attachment types which do not carry media files.
+var nonMediaAttachmentTypes = []string{"Link", "Page", "PropertyValue"}
+
+type attachmentCountMatcher struct {
+	condition CountCondition
+}
+
+func NewAttachmentCountMatcher(condition CountCondition) (*attachmentCountMatcher, error) {
+	if err := condition.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid condition: %w", err)
+	}
+	return &attachmentCountMatcher{
+		condition: condition,
+	}, nil
+}
+
+func (m *attachmentCountMatcher) Test(req *ProxyRequest) (bool, error) {
+	attachments, err := mediaAttachments(req)
+	if err != nil {
+		return false, err
+	}
+	return m.condition.Match(len(attachments)), nil
+}
+
+type attachmentMediaTypeMatcher struct {
+	pattern StringPattern
+}
+
+func NewAttachmentMediaTypeMatcher(pattern StringPattern) (*attachmentMediaTypeMatcher, error) {
+	if pattern == nil {
+		return nil, fmt.Errorf("nil pattern")
+	}
+	return &attachmentMediaTypeMatcher{
+		pattern: pattern,
+	}, nil
+}
+
+func (m *attachmentMediaTypeMatcher) Test(req *ProxyRequest) (bool, error) {
+	return anyMediaAttachment(req, func(attachment ActivityAttachment) bool {
+		return attachment.MediaType != "" && m.pattern.Match(attachment.MediaType)
+	})
+}
+
+type attachmentBlurhashMatcher struct {
+	blurhashes map[string]struct{}
+}
+
+func NewAttachmentBlurhashMatcher(blurhashes []string) (*attachmentBlurhashMatcher, error) {
+	if len(blurhashes) == 0 {
+		return nil, fmt.Errorf("empty blurhashes")
+	}
+	set := make(map[string]struct{}, len(blurhashes))
+	for _, blurhash := range blurhashes {
+		if blurhash == "" {
+			return nil, fmt.Errorf("empty blurhash")
+		}
+		set[blurhash] = struct{}{}
+	}
+	return &attachmentBlurhashMatcher{
+		blurhashes: set,
+	}, nil
+}
+
+func (m *attachmentBlurhashMatcher) Test(req *ProxyRequest) (bool, error) {
+	return anyMediaAttachment(req, func(attachment ActivityAttachment) bool {
+		_, ok := m.blurhashes[attachment.Blurhash]
+		return ok
+	})
+}
+
+type attachmentHostMatcher struct {
+	domains *DomainSet
+}
+
+func NewAttachmentHostMatcher(domains *DomainSet) (*attachmentHostMatcher, error) {
+	if domains == nil {
+		return nil, fmt.Errorf("nil domain set")
+	}
+	return &attachmentHostMatcher{
+		domains: domains,
+	}, nil
+}
+
+func (m *attachmentHostMatcher) Test(req *ProxyRequest) (bool, error) {
+	return anyMediaAttachment(req, func(attachment ActivityAttachment) bool {
+		u, err := url.Parse(attachment.URL)
+		if err != nil {
+			return false
+		}
+		return m.domains.Contains(u.Hostname())
+	})
+}
+
+type attachmentMissingAltMatcher struct{}
+
+func NewAttachmentMissingAltMatcher() (*attachmentMissingAltMatcher, error) {
+	return &attachmentMissingAltMatcher{}, nil
+}
+
+func (m *attachmentMissingAltMatcher) Test(req *ProxyRequest) (bool, error) {
+	return anyMediaAttachment(req, func(attachment ActivityAttachment) bool {
+		return strings.TrimSpace(attachment.Name) == ""
+	})
+}
+
+type attachmentURLMatcher struct {
+	pattern StringPattern
+	// fileNameOnly restricts the pattern to the last element of the url path.
+	fileNameOnly bool
+}
+
+func NewAttachmentURLMatcher(pattern StringPattern) (*attachmentURLMatcher, error) {
+	if pattern == nil {
+		return nil, fmt.Errorf("nil pattern")
+	}
+	return &attachmentURLMatcher{
+		pattern: pattern,
+	}, nil
+}
+
+func NewAttachmentFileNameMatcher(pattern StringPattern) (*attachmentURLMatcher, error) {
+	if pattern == nil {
+		return nil, fmt.Errorf("nil pattern")
+	}
+	return &attachmentURLMatcher{
+		pattern:      pattern,
+		fileNameOnly: true,
+	}, nil
+}
+
+func (m *attachmentURLMatcher) Test(req *ProxyRequest) (bool, error) {
+	return anyMediaAttachment(req, func(attachment ActivityAttachment) bool {
+		if attachment.URL == "" {
+			return false
+		}
+		if !m.fileNameOnly {
+			return m.pattern.Match(attachment.URL)
+		}
+		u, err := url.Parse(attachment.URL)
+		if err != nil || u.Path == "" {
+			return false
+		}
+		return m.pattern.Match(path.Base(u.Path))
+	})
+}
+
+// mediaAttachments returns the media attachments of the object posted to an inbox.
+func mediaAttachments(req *ProxyRequest) ([]ActivityAttachment, error) {
+	object, err := postedObject(req)
+	if err != nil || object == nil {
+		return nil, err
+	}
+	attachments := []ActivityAttachment{}
+	for _, attachment := range object.Attachment {
+		if isMediaAttachment(attachment) {
+			attachments = append(attachments, attachment)
+		}
+	}
+	return attachments, nil
+}
+
+func anyMediaAttachment(req *ProxyRequest, match func(attachment ActivityAttachment) bool) (bool, error) {
+	attachments, err := mediaAttachments(req)
+	if err != nil {
+		return false, err
+	}
+	for _, attachment := range attachments {
+		if match(attachment) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func isMediaAttachment(attachment ActivityAttachment) bool {
+	for _, t := range nonMediaAttachmentTypes {
+		if attachment.Type == t {
+			return false
+		}
+	}
+	return true
+}
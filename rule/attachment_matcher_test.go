@@ -0,0 +1,232 @@
+package rule_test
+
+import (
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func testAttachments() []map[string]any {
+	return []map[string]any{
+		{
+			"type":      "Document",
+			"mediaType": "image/png",
+			"url":       "https://files.spam.example/media/original/ad_banner.png",
+			"name":      nil,
+			"blurhash":  "UBL_:rOpGG-oBUNG,qRj2so|=eE1w^n4S5NH",
+			"width":     640,
+			"height":    480,
+		},
+		{
+			"type":      "Document",
+			"mediaType": "video/mp4",
+			"url":       "https://media.example.com/clip.mp4?v=1",
+			"name":      "a clip",
+		},
+		{
+			"type":  "PropertyValue",
+			"name":  "Website",
+			"value": "https://example.com/",
+		},
+	}
+}
+
+func TestAttachmentCountMatcher_Test(t *testing.T) {
+	cases := []struct {
+		name         string
+		path         string
+		activityType string
+		moreThan     int
+		wantResult   bool
+	}{
+		{
+			name:         "more media than threshold",
+			path:         "/inbox",
+			activityType: "Create",
+			moreThan:     1,
+			wantResult:   true,
+		},
+		{
+			name:         "media equal to threshold excluding non-media attachments",
+			path:         "/inbox",
+			activityType: "Create",
+			moreThan:     2,
+			wantResult:   false,
+		},
+		{
+			name:         "edited note",
+			path:         "/users/alice/inbox",
+			activityType: "Update",
+			moreThan:     1,
+			wantResult:   true,
+		},
+		{
+			name:         "not Create or Update activity",
+			path:         "/inbox",
+			activityType: "Announce",
+			moreThan:     0,
+			wantResult:   false,
+		},
+		{
+			name:         "request other than inbox",
+			path:         "/api/v1/statuses",
+			activityType: "Create",
+			moreThan:     0,
+			wantResult:   false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := rule.NewAttachmentCountMatcher(rule.CountCondition{GreaterThan: &tt.moreThan})
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			got, err := m.Test(newObjectRequest(t, tt.path, tt.activityType, map[string]any{"type": "Note", "content": "", "attachment": testAttachments()}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestAttachmentMatchers_Test(t *testing.T) {
+	mustPattern := func(p rule.StringPattern, err error) rule.StringPattern {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("create pattern: %v", err)
+		}
+		return p
+	}
+	spamDomains, err := rule.NewDomainSet([]string{"*.spam.example"})
+	if err != nil {
+		t.Fatalf("create domain set: %v", err)
+	}
+	otherDomains, err := rule.NewDomainSet([]string{"other.example"})
+	if err != nil {
+		t.Fatalf("create domain set: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		newMatcher func() (rule.RuleMatcher, error)
+		attachment any
+		wantResult bool
+	}{
+		{
+			name: "media type matches",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewAttachmentMediaTypeMatcher(mustPattern(rule.NewPrefixPattern("video/", false)))
+			},
+			attachment: testAttachments(),
+			wantResult: true,
+		},
+		{
+			name: "media type does not match",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewAttachmentMediaTypeMatcher(mustPattern(rule.NewEqualsPattern("image/gif", false)))
+			},
+			attachment: testAttachments(),
+			wantResult: false,
+		},
+		{
+			name: "blurhash is listed",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewAttachmentBlurhashMatcher([]string{"LNJRyVof~qj[ayayj[j[%Mj[WBay", "UBL_:rOpGG-oBUNG,qRj2so|=eE1w^n4S5NH"})
+			},
+			attachment: testAttachments(),
+			wantResult: true,
+		},
+		{
+			name: "blurhash is not listed",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewAttachmentBlurhashMatcher([]string{"LNJRyVof~qj[ayayj[j[%Mj[WBay"})
+			},
+			attachment: testAttachments(),
+			wantResult: false,
+		},
+		{
+			name: "attachment host is listed",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewAttachmentHostMatcher(spamDomains)
+			},
+			attachment: testAttachments(),
+			wantResult: true,
+		},
+		{
+			name: "attachment host is not listed",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewAttachmentHostMatcher(otherDomains)
+			},
+			attachment: testAttachments(),
+			wantResult: false,
+		},
+		{
+			name: "attachment without alt text",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewAttachmentMissingAltMatcher()
+			},
+			attachment: testAttachments(),
+			wantResult: true,
+		},
+		{
+			name: "all attachments have alt text",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewAttachmentMissingAltMatcher()
+			},
+			attachment: map[string]any{"type": "Image", "url": "https://example.com/a.png", "name": "a cat"},
+			wantResult: false,
+		},
+		{
+			name: "no attachments",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewAttachmentMissingAltMatcher()
+			},
+			attachment: nil,
+			wantResult: false,
+		},
+		{
+			name: "url matches",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewAttachmentURLMatcher(mustPattern(rule.NewRegexpPattern(`/media/original/`, false)))
+			},
+			attachment: testAttachments(),
+			wantResult: true,
+		},
+		{
+			name: "file name matches",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewAttachmentFileNameMatcher(mustPattern(rule.NewEqualsPattern("clip.mp4", false)))
+			},
+			attachment: testAttachments(),
+			wantResult: true,
+		},
+		{
+			name: "file name does not match directory",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewAttachmentFileNameMatcher(mustPattern(rule.NewContainsPattern("media", false)))
+			},
+			attachment: testAttachments(),
+			wantResult: false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := tt.newMatcher()
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			got, err := m.Test(newObjectRequest(t, "/inbox", "Create", map[string]any{"type": "Note", "content": "", "attachment": tt.attachment}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
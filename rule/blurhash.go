@@ -0,0 +1,78 @@
+package rule
+
+import (
+	"fmt"
+	"strings"
+)
+
+const blurhashCharacters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// blurhashComponents holds the quantized values encoded in a blurhash.
+type blurhashComponents struct {
+	numX, numY int
+	// values holds the maximum AC value, the RGB of the DC component and the RGB of the AC components.
+	values []int
+}
+
+func decodeBlurhash(hash string) (blurhashComponents, error) {
+	if len(hash) < 6 {
+		return blurhashComponents{}, fmt.Errorf("blurhash too short: %s", hash)
+	}
+	sizeFlag, err := decodeBase83(hash[0:1])
+	if err != nil {
+		return blurhashComponents{}, err
+	}
+	numX, numY := sizeFlag%9+1, sizeFlag/9+1
+	if len(hash) != 4+2*numX*numY {
+		return blurhashComponents{}, fmt.Errorf("invalid blurhash length: %s", hash)
+	}
+
+	components := blurhashComponents{
+		numX:   numX,
+		numY:   numY,
+		values: make([]int, 0, 1+3*numX*numY),
+	}
+	maxAC, err := decodeBase83(hash[1:2])
+	if err != nil {
+		return blurhashComponents{}, err
+	}
+	dc, err := decodeBase83(hash[2:6])
+	if err != nil {
+		return blurhashComponents{}, err
+	}
+	components.values = append(components.values, maxAC, dc>>16, (dc>>8)&0xff, dc&0xff)
+	for i := 6; i < len(hash); i += 2 {
+		ac, err := decodeBase83(hash[i : i+2])
+		if err != nil {
+			return blurhashComponents{}, err
+		}
+		components.values = append(components.values, ac/(19*19), (ac/19)%19, ac%19)
+	}
+	return components, nil
+}
+
+// distance returns the number of differing values, or -1 if the numbers of components differ.
+func (c blurhashComponents) distance(other blurhashComponents) int {
+	if c.numX != other.numX || c.numY != other.numY {
+		return -1
+	}
+	d := 0
+	for i := range c.values {
+		if c.values[i] != other.values[i] {
+			d++
+		}
+	}
+	return d
+}
+
+func decodeBase83(s string) (int, error) {
+	value := 0
+	for _, r := range s {
+		digit := strings.IndexRune(blurhashCharacters, r)
+		if digit < 0 {
+			return 0, fmt.Errorf("invalid blurhash character: %q", r)
+		}
+		value = value*83 + digit
+	}
+	return value, nil
+}
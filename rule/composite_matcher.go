@@ -0,0 +1,76 @@
+package rule
+
+import "fmt"
+
+type allOfMatcher struct {
+	matchers []RuleMatcher
+}
+
+func NewAllOfMatcher(matchers []RuleMatcher) (*allOfMatcher, error) {
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("empty matchers")
+	}
+	return &allOfMatcher{
+		matchers: matchers,
+	}, nil
+}
+
+func (m *allOfMatcher) Test(req *ProxyRequest) (bool, error) {
+	for _, matcher := range m.matchers {
+		matched, err := matcher.Test(req)
+		if err != nil {
+			return false, err
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+type anyOfMatcher struct {
+	matchers []RuleMatcher
+}
+
+func NewAnyOfMatcher(matchers []RuleMatcher) (*anyOfMatcher, error) {
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("empty matchers")
+	}
+	return &anyOfMatcher{
+		matchers: matchers,
+	}, nil
+}
+
+func (m *anyOfMatcher) Test(req *ProxyRequest) (bool, error) {
+	for _, matcher := range m.matchers {
+		matched, err := matcher.Test(req)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type notMatcher struct {
+	matcher RuleMatcher
+}
+
+func NewNotMatcher(matcher RuleMatcher) (*notMatcher, error) {
+	if matcher == nil {
+		return nil, fmt.Errorf("nil matcher")
+	}
+	return &notMatcher{
+		matcher: matcher,
+	}, nil
+}
+
+func (m *notMatcher) Test(req *ProxyRequest) (bool, error) {
+	matched, err := m.matcher.Test(req)
+	if err != nil {
+		return false, err
+	}
+	return !matched, nil
+}
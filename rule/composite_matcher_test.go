@@ -0,0 +1,149 @@
+package rule_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+type staticMatcher struct {
+	result bool
+	err    error
+}
+
+func (m *staticMatcher) Test(req *rule.ProxyRequest) (bool, error) {
+	return m.result, m.err
+}
+
+var (
+	matchTrue  = &staticMatcher{result: true}
+	matchFalse = &staticMatcher{result: false}
+	matchError = &staticMatcher{err: fmt.Errorf("test error")}
+)
+
+func TestCompositeMatchers(t *testing.T) {
+	newAllOf := func(matchers ...rule.RuleMatcher) func() (rule.RuleMatcher, error) {
+		return func() (rule.RuleMatcher, error) { return rule.NewAllOfMatcher(matchers) }
+	}
+	newAnyOf := func(matchers ...rule.RuleMatcher) func() (rule.RuleMatcher, error) {
+		return func() (rule.RuleMatcher, error) { return rule.NewAnyOfMatcher(matchers) }
+	}
+	newNot := func(matcher rule.RuleMatcher) func() (rule.RuleMatcher, error) {
+		return func() (rule.RuleMatcher, error) { return rule.NewNotMatcher(matcher) }
+	}
+
+	cases := []struct {
+		name       string
+		newMatcher func() (rule.RuleMatcher, error)
+		wantResult bool
+		wantErr    bool
+	}{
+		{
+			name:       "all_of matches when all matchers match",
+			newMatcher: newAllOf(matchTrue, matchTrue),
+			wantResult: true,
+		},
+		{
+			name:       "all_of does not match when any matcher does not match",
+			newMatcher: newAllOf(matchTrue, matchFalse),
+			wantResult: false,
+		},
+		{
+			name:       "all_of stops at first unmatched matcher",
+			newMatcher: newAllOf(matchFalse, matchError),
+			wantResult: false,
+		},
+		{
+			name:       "any_of matches when any matcher matches",
+			newMatcher: newAnyOf(matchFalse, matchTrue),
+			wantResult: true,
+		},
+		{
+			name:       "any_of does not match when no matcher matches",
+			newMatcher: newAnyOf(matchFalse, matchFalse),
+			wantResult: false,
+		},
+		{
+			name:       "any_of stops at first matched matcher",
+			newMatcher: newAnyOf(matchTrue, matchError),
+			wantResult: true,
+		},
+		{
+			name:       "any_of returns error from matcher",
+			newMatcher: newAnyOf(matchFalse, matchError),
+			wantErr:    true,
+		},
+		{
+			name:       "not inverts matched result",
+			newMatcher: newNot(matchTrue),
+			wantResult: false,
+		},
+		{
+			name:       "not inverts unmatched result",
+			newMatcher: newNot(matchFalse),
+			wantResult: true,
+		},
+		{
+			name:       "not returns error from matcher",
+			newMatcher: newNot(matchError),
+			wantErr:    true,
+		},
+		{
+			name: "nested groups",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				anyOf, err := rule.NewAnyOfMatcher([]rule.RuleMatcher{matchFalse, matchTrue})
+				if err != nil {
+					return nil, err
+				}
+				not, err := rule.NewNotMatcher(matchFalse)
+				if err != nil {
+					return nil, err
+				}
+				return rule.NewAllOfMatcher([]rule.RuleMatcher{anyOf, not})
+			},
+			wantResult: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := tt.newMatcher()
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+
+			req, err := http.NewRequest("GET", "/", nil)
+			if err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+
+			gotResult, err := m.Test(rule.NewProxyRequest(req))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("test: %v", err)
+			}
+			if tt.wantResult != gotResult {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, gotResult)
+			}
+		})
+	}
+}
+
+func TestCompositeMatchers_EmptyMatchers(t *testing.T) {
+	if _, err := rule.NewAllOfMatcher(nil); err == nil {
+		t.Errorf("expected error for empty all_of, but got nil")
+	}
+	if _, err := rule.NewAnyOfMatcher(nil); err == nil {
+		t.Errorf("expected error for empty any_of, but got nil")
+	}
+	if _, err := rule.NewNotMatcher(nil); err == nil {
+		t.Errorf("expected error for empty not, but got nil")
+	}
+}
@@ -0,0 +1,65 @@
+package rule
+
+import (
+	"fmt"
+	"math"
+)
+
+// CountCondition compares a count with the given bounds. Nil bounds are not checked.
+type CountCondition struct {
+	GreaterThan        *int
+	GreaterThanOrEqual *int
+	LessThan           *int
+	LessThanOrEqual    *int
+	Equal              *int
+}
+
+func (c CountCondition) Validate() error {
+	bounds := []*int{c.GreaterThan, c.GreaterThanOrEqual, c.LessThan, c.LessThanOrEqual, c.Equal}
+	specified := 0
+	for _, bound := range bounds {
+		if bound == nil {
+			continue
+		}
+		if *bound < 0 {
+			return fmt.Errorf("invalid count: %d", *bound)
+		}
+		specified++
+	}
+	if specified == 0 {
+		return fmt.Errorf("empty condition")
+	}
+	if c.Equal != nil && specified > 1 {
+		return fmt.Errorf("eq cannot be combined with other comparisons")
+	}
+	if min, max := c.bounds(); min > max {
+		return fmt.Errorf("no count satisfies the condition")
+	}
+	return nil
+}
+
+func (c CountCondition) Match(count int) bool {
+	min, max := c.bounds()
+	return min <= count && count <= max
+}
+
+// bounds returns the inclusive range of counts satisfying the condition.
+func (c CountCondition) bounds() (int, int) {
+	min, max := 0, math.MaxInt
+	if c.Equal != nil {
+		return *c.Equal, *c.Equal
+	}
+	if c.GreaterThan != nil {
+		min = *c.GreaterThan + 1
+	}
+	if c.GreaterThanOrEqual != nil && *c.GreaterThanOrEqual > min {
+		min = *c.GreaterThanOrEqual
+	}
+	if c.LessThan != nil {
+		max = *c.LessThan - 1
+	}
+	if c.LessThanOrEqual != nil && *c.LessThanOrEqual < max {
+		max = *c.LessThanOrEqual
+	}
+	return min, max
+}
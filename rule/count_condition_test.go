@@ -0,0 +1,60 @@
+package rule_test
+
+import (
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestCountCondition_Match(t *testing.T) {
+	cases := []struct {
+		name      string
+		condition rule.CountCondition
+		count     int
+		want      bool
+	}{
+		{name: "gt satisfied", condition: rule.CountCondition{GreaterThan: intPtr(2)}, count: 3, want: true},
+		{name: "gt equal", condition: rule.CountCondition{GreaterThan: intPtr(2)}, count: 2, want: false},
+		{name: "gte equal", condition: rule.CountCondition{GreaterThanOrEqual: intPtr(2)}, count: 2, want: true},
+		{name: "lt equal", condition: rule.CountCondition{LessThan: intPtr(2)}, count: 2, want: false},
+		{name: "lt zero count", condition: rule.CountCondition{LessThan: intPtr(2)}, count: 0, want: true},
+		{name: "lte equal", condition: rule.CountCondition{LessThanOrEqual: intPtr(2)}, count: 2, want: true},
+		{name: "eq", condition: rule.CountCondition{Equal: intPtr(0)}, count: 0, want: true},
+		{name: "eq differs", condition: rule.CountCondition{Equal: intPtr(0)}, count: 1, want: false},
+		{name: "range lower bound", condition: rule.CountCondition{GreaterThanOrEqual: intPtr(2), LessThanOrEqual: intPtr(4)}, count: 2, want: true},
+		{name: "range upper bound", condition: rule.CountCondition{GreaterThanOrEqual: intPtr(2), LessThanOrEqual: intPtr(4)}, count: 4, want: true},
+		{name: "out of range", condition: rule.CountCondition{GreaterThanOrEqual: intPtr(2), LessThanOrEqual: intPtr(4)}, count: 5, want: false},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.condition.Validate(); err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			if got := tt.condition.Match(tt.count); tt.want != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCountCondition_Validate(t *testing.T) {
+	cases := []struct {
+		name      string
+		condition rule.CountCondition
+	}{
+		{name: "empty", condition: rule.CountCondition{}},
+		{name: "negative count", condition: rule.CountCondition{GreaterThan: intPtr(-1)}},
+		{name: "eq with other comparison", condition: rule.CountCondition{Equal: intPtr(1), LessThan: intPtr(3)}},
+		{name: "empty range", condition: rule.CountCondition{GreaterThan: intPtr(3), LessThan: intPtr(4)}},
+		{name: "less than zero", condition: rule.CountCondition{LessThan: intPtr(0)}},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.condition.Validate(); err == nil {
+				t.Errorf("expected error, but got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package rule
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// DomainSet is a set of domains.
+// A domain prefixed with "*." matches its subdomains.
+type DomainSet struct {
+	domains    map[string]struct{}
+	subdomains map[string]struct{}
+}
+
+func NewDomainSet(domains []string) (*DomainSet, error) {
+	set := &DomainSet{
+		domains:    map[string]struct{}{},
+		subdomains: map[string]struct{}{},
+	}
+	for _, domain := range domains {
+		if err := set.add(domain); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+func (s *DomainSet) add(domain string) error {
+	domain = normalizeDomain(domain)
+	if parent, ok := strings.CutPrefix(domain, "*."); ok {
+		if parent == "" || strings.Contains(parent, "*") {
+			return fmt.Errorf("invalid domain: %s", domain)
+		}
+		s.subdomains[parent] = struct{}{}
+		return nil
+	}
+	if domain == "" || strings.Contains(domain, "*") {
+		return fmt.Errorf("invalid domain: %s", domain)
+	}
+	s.domains[domain] = struct{}{}
+	return nil
+}
+
+func (s *DomainSet) Contains(host string) bool {
+	host = normalizeDomain(host)
+	if host == "" {
+		return false
+	}
+	if _, ok := s.domains[host]; ok {
+		return true
+	}
+	for {
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			return false
+		}
+		if _, ok := s.subdomains[parent]; ok {
+			return true
+		}
+		host = parent
+	}
+}
+
+// normalizeDomain lowercases the domain, and converts internationalized domain names into punycode.
+func normalizeDomain(domain string) string {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	for _, r := range domain {
+		if r >= utf8.RuneSelf {
+			if ascii, err := idna.ToASCII(domain); err == nil {
+				return ascii
+			}
+			break
+		}
+	}
+	return domain
+}
+
+func LoadDomainFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+	return ParseDomainList(f)
+}
+
+// ParseDomainList reads a plain text list with one domain per line, or a domain_blocks CSV exported by Mastodon.
+// Blocks in CSV also cover their subdomains, and obfuscated or non-suspending blocks are skipped.
+func ParseDomainList(r io.Reader) ([]string, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read stream: %w", err)
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("#domain")) {
+		return parseDomainBlocksCSV(bytes.NewReader(body))
+	}
+	return parseDomainText(bytes.NewReader(body))
+}
+
+func parseDomainText(r io.Reader) ([]string, error) {
+	domains := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan lines: %w", err)
+	}
+	return domains, nil
+}
+
+// parseDomainBlocksCSV reads domain blocks exported from Mastodon.
+// Only suspended domains are read, since silenced domains are still allowed to deliver activities.
+// Domains containing * cannot be matched and are skipped.
+func parseDomainBlocksCSV(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.TrimPrefix(strings.TrimSpace(name), "#")] = i
+	}
+	column := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	domains := []string{}
+	for _, record := range records[1:] {
+		domain := column(record, "domain")
+		if domain == "" || strings.Contains(domain, "*") {
+			continue
+		}
+		if severity := column(record, "severity"); severity != "" && severity != "suspend" {
+			continue
+		}
+		domains = append(domains, domain, "*."+domain)
+	}
+	return domains, nil
+}
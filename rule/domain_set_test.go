@@ -0,0 +1,99 @@
+package rule_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestDomainSet_Contains(t *testing.T) {
+	set, err := rule.NewDomainSet([]string{"example.com", "*.example.org", "Upper.Example.NET."})
+	if err != nil {
+		t.Fatalf("create domain set: %v", err)
+	}
+
+	cases := []struct {
+		host string
+		want bool
+	}{
+		{host: "example.com", want: true},
+		{host: "EXAMPLE.COM", want: true},
+		{host: "sub.example.com", want: false},
+		{host: "example.org", want: false},
+		{host: "sub.example.org", want: true},
+		{host: "deep.sub.example.org", want: true},
+		{host: "notexample.org", want: false},
+		{host: "upper.example.net", want: true},
+		{host: "", want: false},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := set.Contains(tt.host); tt.want != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewDomainSet_InvalidDomain(t *testing.T) {
+	for _, domain := range []string{"", "*.", "ex*mple.com", "*.*.example.com"} {
+		if _, err := rule.NewDomainSet([]string{domain}); err == nil {
+			t.Errorf("expected error for %q, but got nil", domain)
+		}
+	}
+}
+
+func TestParseDomainList(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "plain text",
+			body: "# blocked instances\nexample.com\n\n*.example.org # with subdomains\n",
+			want: []string{"example.com", "*.example.org"},
+		},
+		{
+			name: "mastodon domain blocks",
+			body: "#domain,#severity,#reject_media,#reject_reports,#public_comment,#obfuscate\n" +
+				"example.com,suspend,true,true,spam,false\n" +
+				"example.org,silence,true,false,,false\n" +
+				"ex*mple.net,suspend,false,false,,true\n" +
+				"hidden.example,suspend,false,false,,true\n" +
+				"\"example.jp\",suspend,false,false,\"spam, harassment\",false\n",
+			want: []string{"example.com", "*.example.com", "hidden.example", "*.hidden.example", "example.jp", "*.example.jp"},
+		},
+		{
+			name: "mastodon domain blocks other than suspend",
+			body: "#domain,#severity,#reject_media,#reject_reports,#public_comment,#obfuscate\n" +
+				"example.org,silence,true,false,,false\n" +
+				"example.net,noop,true,true,,false\n",
+			want: []string{},
+		},
+		{
+			name: "domain only csv",
+			body: "#domain\nexample.com\n",
+			want: []string{"example.com", "*.example.com"},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.ParseDomainList(strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(tt.want) != len(got) {
+				t.Fatalf("unexpected result: want %v, but got %v", tt.want, got)
+			}
+			for i := range tt.want {
+				if tt.want[i] != got[i] {
+					t.Errorf("unexpected result: want %v, but got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package rule
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const simHashShingleSize = 4
+
+type DuplicateNoteConfig struct {
+	// Window is the duration to remember delivered notes.
+	Window time.Duration
+	// Distance is the maximum hamming distance of the fingerprints regarded as near-identical.
+	Distance int
+	// MaxEntries bounds the number of remembered notes. The oldest note is dropped first.
+	MaxEntries int
+}
+
+// DuplicateNoteStore remembers delivered notes grouped by near-identical content.
+// It is kept apart from the matcher so that the notes survive rule reloads.
+type DuplicateNoteStore struct {
+	conf DuplicateNoteConfig
+	now  func() time.Time
+
+	mu sync.Mutex
+	// entries are the remembered notes in the order of observation.
+	entries []noteFingerprint
+	// clusters are groups of near-identical notes keyed by the fingerprint of the first note.
+	clusters map[uint64]*noteCluster
+}
+
+type noteFingerprint struct {
+	cluster    uint64
+	actor      string
+	observedAt time.Time
+}
+
+type noteCluster struct {
+	// actors holds the number of remembered notes per actor.
+	actors map[string]int
+}
+
+// NewDuplicateNoteStore creates a store. now is used as the clock, and time.Now is used when nil.
+func NewDuplicateNoteStore(conf DuplicateNoteConfig, now func() time.Time) (*DuplicateNoteStore, error) {
+	if conf.Window <= 0 {
+		return nil, fmt.Errorf("invalid window: %v", conf.Window)
+	}
+	if conf.Distance < 0 || conf.Distance > 64 {
+		return nil, fmt.Errorf("invalid distance: %d", conf.Distance)
+	}
+	if conf.MaxEntries <= 0 {
+		return nil, fmt.Errorf("invalid max entries: %d", conf.MaxEntries)
+	}
+	if now == nil {
+		now = time.Now
+	}
+	return &DuplicateNoteStore{
+		conf:     conf,
+		now:      now,
+		clusters: map[uint64]*noteCluster{},
+	}, nil
+}
+
+type duplicateNoteMatcher struct {
+	store      *DuplicateNoteStore
+	condition  CountCondition
+	normalizer *TextNormalizer
+}
+
+// NewDuplicateNoteMatcher creates a matcher detecting near-identical notes delivered by many actors.
+// condition is tested against the number of distinct actors which delivered the note.
+func NewDuplicateNoteMatcher(store *DuplicateNoteStore, condition CountCondition) (*duplicateNoteMatcher, error) {
+	if store == nil {
+		return nil, fmt.Errorf("nil store")
+	}
+	if err := condition.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid condition: %w", err)
+	}
+	// confusables are folded as well since the text is only compared with other notes
+	normalizer, err := NewTextNormalizer(normalizeSteps)
+	if err != nil {
+		return nil, fmt.Errorf("create normalizer: %w", err)
+	}
+	return &duplicateNoteMatcher{
+		store:      store,
+		condition:  condition,
+		normalizer: normalizer,
+	}, nil
+}
+
+func (m *duplicateNoteMatcher) Test(req *ProxyRequest) (bool, error) {
+	object, err := postedObject(req)
+	if err != nil || object == nil {
+		return false, err
+	}
+
+	text := m.normalizeNoteText(object.Content)
+	if text == "" {
+		return false, nil
+	}
+	activity, err := req.Activity()
+	if err != nil {
+		return false, fmt.Errorf("parse activity: %w", err)
+	}
+	if activity.Actor == "" {
+		return false, nil
+	}
+	return m.condition.Match(m.store.observe(simHash(text), activity.Actor)), nil
+}
+
+// observe remembers the note, and returns the number of distinct actors of the near-identical notes.
+func (s *DuplicateNoteStore) observe(hash uint64, actor string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	expired := 0
+	for expired < len(s.entries) && now.Sub(s.entries[expired].observedAt) > s.conf.Window {
+		expired++
+	}
+	if overflow := len(s.entries) - expired + 1 - s.conf.MaxEntries; overflow > 0 {
+		expired += overflow
+	}
+	for _, entry := range s.entries[:expired] {
+		s.forget(entry)
+	}
+
+	key, cluster := s.findCluster(hash)
+	if cluster == nil {
+		key, cluster = hash, &noteCluster{actors: map[string]int{}}
+		s.clusters[key] = cluster
+	}
+	cluster.actors[actor]++
+	s.entries = append(s.entries[expired:], noteFingerprint{cluster: key, actor: actor, observedAt: now})
+	return len(cluster.actors)
+}
+
+// findCluster returns the nearest cluster of notes near-identical to the fingerprint.
+func (s *DuplicateNoteStore) findCluster(hash uint64) (uint64, *noteCluster) {
+	if cluster, ok := s.clusters[hash]; ok {
+		return hash, cluster
+	}
+	var nearestKey uint64
+	var nearest *noteCluster
+	nearestDistance := s.conf.Distance + 1
+	for key, cluster := range s.clusters {
+		if d := bits.OnesCount64(key ^ hash); d < nearestDistance {
+			nearestKey, nearest, nearestDistance = key, cluster, d
+		}
+	}
+	return nearestKey, nearest
+}
+
+func (s *DuplicateNoteStore) forget(entry noteFingerprint) {
+	cluster, ok := s.clusters[entry.cluster]
+	if !ok {
+		return
+	}
+	if cluster.actors[entry.actor]--; cluster.actors[entry.actor] <= 0 {
+		delete(cluster.actors, entry.actor)
+	}
+	if len(cluster.actors) == 0 {
+		delete(s.clusters, entry.cluster)
+	}
+}
+
+// simHash returns the 64-bit SimHash of the text over character shingles.
+func simHash(text string) uint64 {
+	runes := []rune(text)
+	size := simHashShingleSize
+	if len(runes) < size {
+		size = len(runes)
+	}
+
+	var weights [64]int
+	for i := 0; i+size <= len(runes); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(string(runes[i : i+size])))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var hash uint64
+	for bit := 0; bit < 64; bit++ {
+		if weights[bit] > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+// normalizeNoteText normalizes note content, and strips whitespace and punctuation from it.
+func (m *duplicateNoteMatcher) normalizeNoteText(content string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) {
+			return -1
+		}
+		return r
+	}, m.normalizer.Normalize(content))
+}
@@ -0,0 +1,159 @@
+package rule_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestDuplicateNoteMatcher(t *testing.T) {
+	const spam = "<p>Check out this amazing offer at <a href=\"https://spam.example\">spam.example</a> now!!! Limited time only, join today</p>"
+
+	type delivery struct {
+		advance time.Duration
+		path    string
+		actor   string
+		content string
+		want    bool
+	}
+	cases := []struct {
+		name       string
+		conf       rule.DuplicateNoteConfig
+		condition  rule.CountCondition
+		deliveries []delivery
+	}{
+		{
+			name:      "matches when more than threshold actors deliver near-identical notes",
+			conf:      rule.DuplicateNoteConfig{Window: time.Minute, Distance: 6, MaxEntries: 100},
+			condition: rule.CountCondition{GreaterThan: intPtr(2)},
+			deliveries: []delivery{
+				{actor: "https://a.example/users/1", content: spam, want: false},
+				{actor: "https://b.example/users/2", content: "<p>check out this amazing offer at spam.example NOW! limited time only, join today 12</p>", want: false},
+				{actor: "https://c.example/users/3", content: "<p>Check out this amazing offer at spam.example now!! Limited time only, join today @alice</p>", want: true},
+				{actor: "https://d.example/users/4", content: "<p>Hello, I had a nice lunch today at the cafe near the station.</p>", want: false},
+			},
+		},
+		{
+			name:      "matches while the number of actors is in the range",
+			conf:      rule.DuplicateNoteConfig{Window: time.Minute, Distance: 6, MaxEntries: 100},
+			condition: rule.CountCondition{GreaterThanOrEqual: intPtr(2), LessThanOrEqual: intPtr(3)},
+			deliveries: []delivery{
+				{actor: "https://a.example/users/1", content: spam, want: false},
+				{actor: "https://b.example/users/2", content: spam, want: true},
+				{actor: "https://c.example/users/3", content: spam, want: true},
+				{actor: "https://d.example/users/4", content: spam, want: false},
+			},
+		},
+		{
+			name:      "notes obfuscated with fullwidth, invisible and lookalike letters are near-identical",
+			conf:      rule.DuplicateNoteConfig{Window: time.Minute, Distance: 6, MaxEntries: 100},
+			condition: rule.CountCondition{GreaterThan: intPtr(1)},
+			deliveries: []delivery{
+				{actor: "https://a.example/users/1", content: spam, want: false},
+				{actor: "https://b.example/users/2", content: "<p>Ｃｈｅｃｋ оut this аmаzing&#8203; offer at spam.example now!!! Limited time only, join today</p>", want: true},
+			},
+		},
+		{
+			name:      "same actor is counted once",
+			conf:      rule.DuplicateNoteConfig{Window: time.Minute, Distance: 6, MaxEntries: 100},
+			condition: rule.CountCondition{GreaterThan: intPtr(1)},
+			deliveries: []delivery{
+				{actor: "https://a.example/users/1", content: spam, want: false},
+				{actor: "https://a.example/users/1", content: spam, want: false},
+				{actor: "https://b.example/users/2", content: spam, want: true},
+			},
+		},
+		{
+			name:      "notes out of window are forgotten",
+			conf:      rule.DuplicateNoteConfig{Window: time.Minute, Distance: 6, MaxEntries: 100},
+			condition: rule.CountCondition{GreaterThan: intPtr(1)},
+			deliveries: []delivery{
+				{actor: "https://a.example/users/1", content: spam, want: false},
+				{advance: 2 * time.Minute, actor: "https://b.example/users/2", content: spam, want: false},
+				{advance: 30 * time.Second, actor: "https://c.example/users/3", content: spam, want: true},
+			},
+		},
+		{
+			name:      "oldest notes are dropped over max entries",
+			conf:      rule.DuplicateNoteConfig{Window: time.Minute, Distance: 6, MaxEntries: 2},
+			condition: rule.CountCondition{GreaterThan: intPtr(1)},
+			deliveries: []delivery{
+				{actor: "https://a.example/users/1", content: spam, want: false},
+				{actor: "https://b.example/users/2", content: "<p>Hello, I had a nice lunch today at the cafe near the station.</p>", want: false},
+				{actor: "https://c.example/users/3", content: "今日はいい天気ですね。散歩に行きました。", want: false},
+				{actor: "https://d.example/users/4", content: spam, want: false},
+			},
+		},
+		{
+			name:      "ignores requests other than inbox",
+			conf:      rule.DuplicateNoteConfig{Window: time.Minute, Distance: 6, MaxEntries: 100},
+			condition: rule.CountCondition{GreaterThan: intPtr(0)},
+			deliveries: []delivery{
+				{path: "/api/v1/statuses", actor: "https://a.example/users/1", content: spam, want: false},
+			},
+		},
+		{
+			name:      "ignores activities without actor",
+			conf:      rule.DuplicateNoteConfig{Window: time.Minute, Distance: 6, MaxEntries: 100},
+			condition: rule.CountCondition{GreaterThan: intPtr(0)},
+			deliveries: []delivery{
+				{actor: "", content: spam, want: false},
+				{actor: "", content: spam, want: false},
+			},
+		},
+		{
+			name:      "ignores empty content",
+			conf:      rule.DuplicateNoteConfig{Window: time.Minute, Distance: 6, MaxEntries: 100},
+			condition: rule.CountCondition{GreaterThan: intPtr(0)},
+			deliveries: []delivery{
+				{actor: "https://a.example/users/1", content: "<p></p>", want: false},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+			store, err := rule.NewDuplicateNoteStore(tt.conf, clock.Now)
+			if err != nil {
+				t.Fatalf("create store: %v", err)
+			}
+			matcher, err := rule.NewDuplicateNoteMatcher(store, tt.condition)
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+
+			for i, d := range tt.deliveries {
+				clock.Advance(d.advance)
+				path := d.path
+				if path == "" {
+					path = "/inbox"
+				}
+				body, err := json.Marshal(map[string]any{
+					"type":   "Create",
+					"actor":  d.actor,
+					"object": map[string]any{"type": "Note", "content": d.content},
+				})
+				if err != nil {
+					t.Fatalf("marshal activity: %v", err)
+				}
+				req, err := http.NewRequest("POST", path, bytes.NewBuffer(body))
+				if err != nil {
+					t.Fatalf("create request: %v", err)
+				}
+
+				got, err := matcher.Test(rule.NewProxyRequest(req))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if d.want != got {
+					t.Errorf("unexpected result of delivery %d: want %v, but got %v", i, d.want, got)
+				}
+			}
+		})
+	}
+}
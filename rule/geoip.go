@@ -0,0 +1,64 @@
+package rule
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// GeoIPDatabase is a MaxMind DB such as GeoLite2 Country or GeoLite2 ASN.
+type GeoIPDatabase struct {
+	reader *maxminddb.Reader
+}
+
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+type asnRecord struct {
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+// LoadGeoIPDatabase reads the whole database into memory so that the file can be replaced while running.
+func LoadGeoIPDatabase(path string) (*GeoIPDatabase, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	reader, err := maxminddb.FromBytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	return &GeoIPDatabase{
+		reader: reader,
+	}, nil
+}
+
+// Country returns the ISO 3166-1 country code of the address, or an empty string when unknown.
+func (db *GeoIPDatabase) Country(ip net.IP) (string, error) {
+	record := countryRecord{}
+	if err := db.reader.Lookup(ip, &record); err != nil {
+		return "", fmt.Errorf("lookup country: %w", err)
+	}
+	if record.Country.ISOCode != "" {
+		return record.Country.ISOCode, nil
+	}
+	return record.RegisteredCountry.ISOCode, nil
+}
+
+// ASN returns the autonomous system number of the address, or 0 when unknown.
+func (db *GeoIPDatabase) ASN(ip net.IP) (uint, error) {
+	record := asnRecord{}
+	if err := db.reader.Lookup(ip, &record); err != nil {
+		return 0, fmt.Errorf("lookup asn: %w", err)
+	}
+	return record.AutonomousSystemNumber, nil
+}
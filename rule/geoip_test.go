@@ -0,0 +1,116 @@
+package rule_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+// writeGeoIPFixture writes a MaxMind DB mapping the networks to the records, and returns its path.
+func writeGeoIPFixture(t *testing.T, databaseType string, records map[string]mmdbtype.Map) string {
+	t.Helper()
+	tree, err := mmdbwriter.New(mmdbwriter.Options{
+		DatabaseType:            databaseType,
+		IncludeReservedNetworks: true,
+	})
+	if err != nil {
+		t.Fatalf("create tree: %v", err)
+	}
+	for cidr, record := range records {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatalf("parse cidr: %v", err)
+		}
+		if err := tree.Insert(network, record); err != nil {
+			t.Fatalf("insert record: %v", err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), databaseType+".mmdb")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create file: %v", err)
+	}
+	defer f.Close()
+	if _, err := tree.WriteTo(f); err != nil {
+		t.Fatalf("write database: %v", err)
+	}
+	return path
+}
+
+func countryRecord(isoCode string) mmdbtype.Map {
+	return mmdbtype.Map{
+		"country": mmdbtype.Map{"iso_code": mmdbtype.String(isoCode)},
+	}
+}
+
+func asnRecord(asn uint32, organization string) mmdbtype.Map {
+	return mmdbtype.Map{
+		"autonomous_system_number":       mmdbtype.Uint32(asn),
+		"autonomous_system_organization": mmdbtype.String(organization),
+	}
+}
+
+func TestGeoIPDatabase(t *testing.T) {
+	countryDB, err := rule.LoadGeoIPDatabase(writeGeoIPFixture(t, "GeoLite2-Country", map[string]mmdbtype.Map{
+		"192.0.2.0/24":  countryRecord("JP"),
+		"2001:db8::/32": countryRecord("US"),
+		"198.51.100.0/24": {
+			"registered_country": mmdbtype.Map{"iso_code": mmdbtype.String("DE")},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("load database: %v", err)
+	}
+	asnDB, err := rule.LoadGeoIPDatabase(writeGeoIPFixture(t, "GeoLite2-ASN", map[string]mmdbtype.Map{
+		"192.0.2.0/24": asnRecord(64500, "Example Hosting"),
+	}))
+	if err != nil {
+		t.Fatalf("load database: %v", err)
+	}
+
+	cases := []struct {
+		ip          string
+		wantCountry string
+		wantASN     uint
+	}{
+		{ip: "192.0.2.1", wantCountry: "JP", wantASN: 64500},
+		{ip: "2001:db8::1", wantCountry: "US", wantASN: 0},
+		{ip: "198.51.100.1", wantCountry: "DE", wantASN: 0},
+		{ip: "203.0.113.1", wantCountry: "", wantASN: 0},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.ip, func(t *testing.T) {
+			country, err := countryDB.Country(net.ParseIP(tt.ip))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantCountry != country {
+				t.Errorf("unexpected country: want %s, but got %s", tt.wantCountry, country)
+			}
+			asn, err := asnDB.ASN(net.ParseIP(tt.ip))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantASN != asn {
+				t.Errorf("unexpected asn: want %d, but got %d", tt.wantASN, asn)
+			}
+		})
+	}
+}
+
+func TestLoadGeoIPDatabase_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invalid.mmdb")
+	if err := os.WriteFile(path, []byte("not a database"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if _, err := rule.LoadGeoIPDatabase(path); err == nil {
+		t.Errorf("expected error, but got nil")
+	}
+}
@@ -0,0 +1,74 @@
+package rule
+
+import (
+	"fmt"
+	"strings"
+)
+
+type hashtagMatcher struct {
+	hashtags map[string]struct{}
+}
+
+// NewHashtagMatcher creates a matcher which matches when the object has any of the hashtags.
+// Hashtags are compared case-insensitively, and the leading # is optional.
+func NewHashtagMatcher(hashtags []string) (*hashtagMatcher, error) {
+	if len(hashtags) == 0 {
+		return nil, fmt.Errorf("empty hashtags")
+	}
+	set := make(map[string]struct{}, len(hashtags))
+	for _, hashtag := range hashtags {
+		name := normalizeHashtag(hashtag)
+		if name == "" {
+			return nil, fmt.Errorf("empty hashtag")
+		}
+		set[name] = struct{}{}
+	}
+	return &hashtagMatcher{
+		hashtags: set,
+	}, nil
+}
+
+func (m *hashtagMatcher) Test(req *ProxyRequest) (bool, error) {
+	return anyHashtag(req, func(name string) bool {
+		_, ok := m.hashtags[strings.ToLower(name)]
+		return ok
+	})
+}
+
+type hashtagPatternMatcher struct {
+	pattern StringPattern
+}
+
+// NewHashtagPatternMatcher creates a matcher which tests hashtag names without the leading #.
+func NewHashtagPatternMatcher(pattern StringPattern) (*hashtagPatternMatcher, error) {
+	if pattern == nil {
+		return nil, fmt.Errorf("nil pattern")
+	}
+	return &hashtagPatternMatcher{
+		pattern: pattern,
+	}, nil
+}
+
+func (m *hashtagPatternMatcher) Test(req *ProxyRequest) (bool, error) {
+	return anyHashtag(req, m.pattern.Match)
+}
+
+func anyHashtag(req *ProxyRequest, match func(name string) bool) (bool, error) {
+	object, err := postedObject(req)
+	if err != nil || object == nil {
+		return false, err
+	}
+	for _, tag := range object.Tag {
+		if tag.Type != "Hashtag" {
+			continue
+		}
+		if name := strings.TrimPrefix(strings.TrimSpace(tag.Name), "#"); name != "" && match(name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func normalizeHashtag(hashtag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(hashtag), "#"))
+}
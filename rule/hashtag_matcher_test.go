@@ -0,0 +1,91 @@
+package rule_test
+
+import (
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestHashtagMatcher_Test(t *testing.T) {
+	cases := []struct {
+		name       string
+		hashtags   []string
+		wantResult bool
+	}{
+		{
+			name:       "listed hashtag in different case",
+			hashtags:   []string{"free"},
+			wantResult: true,
+		},
+		{
+			name:       "listed hashtag with leading #",
+			hashtags:   []string{"#GiveAway"},
+			wantResult: true,
+		},
+		{
+			name:       "mention is not hashtag",
+			hashtags:   []string{"@alice@example.com"},
+			wantResult: false,
+		},
+		{
+			name:       "hashtag is not listed",
+			hashtags:   []string{"cats"},
+			wantResult: false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := rule.NewHashtagMatcher(tt.hashtags)
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			got, err := m.Test(newObjectRequest(t, "/inbox", "Create", map[string]any{"type": "Note", "content": "", "tag": testTags()}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestHashtagPatternMatcher_Test(t *testing.T) {
+	cases := []struct {
+		name       string
+		expr       string
+		wantResult bool
+	}{
+		{
+			name:       "hashtag matches without leading #",
+			expr:       `^follow`,
+			wantResult: true,
+		},
+		{
+			name:       "pattern with # does not match",
+			expr:       `^#`,
+			wantResult: false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, err := rule.NewRegexpPattern(tt.expr, false)
+			if err != nil {
+				t.Fatalf("create pattern: %v", err)
+			}
+			m, err := rule.NewHashtagPatternMatcher(pattern)
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			got, err := m.Test(newObjectRequest(t, "/inbox", "Create", map[string]any{"type": "Note", "content": "", "tag": testTags()}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
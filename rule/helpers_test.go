@@ -0,0 +1,78 @@
+package rule_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/httpsig"
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+// newObjectRequest creates a request delivering an activity of activityType with the object by bob.
+func newObjectRequest(t *testing.T, path string, activityType string, object map[string]any) *rule.ProxyRequest {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{
+		"type":   activityType,
+		"actor":  "https://example.com/users/bob",
+		"object": object,
+	})
+	if err != nil {
+		t.Fatalf("marshal json: %v", err)
+	}
+	req, err := http.NewRequest("POST", path, bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	return rule.NewProxyRequest(req)
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// signRequest signs the request with the given headers, setting the Digest header when body is given.
+func signRequest(r *http.Request, keyID string, key crypto.Signer, headers []string, body []byte) error {
+	if len(body) > 0 {
+		digest := sha256.Sum256(body)
+		r.Header.Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(digest[:]))
+	}
+
+	sig := &httpsig.Signature{
+		KeyID:   keyID,
+		Headers: headers,
+	}
+	signingString, err := sig.SigningString(r)
+	if err != nil {
+		return fmt.Errorf("build signing string: %w", err)
+	}
+
+	var signature []byte
+	switch key.Public().(type) {
+	case *rsa.PublicKey:
+		sig.Algorithm = "rsa-sha256"
+		hashed := sha256.Sum256([]byte(signingString))
+		signature, err = key.Sign(rand.Reader, hashed[:], crypto.SHA256)
+	case ed25519.PublicKey:
+		sig.Algorithm = "ed25519"
+		signature, err = key.Sign(rand.Reader, []byte(signingString), crypto.Hash(0))
+	default:
+		return fmt.Errorf("unsupported key type: %T", key.Public())
+	}
+	if err != nil {
+		return fmt.Errorf("sign: %w", err)
+	}
+
+	r.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="%s",headers="%s",signature="%s"`,
+		keyID, sig.Algorithm, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
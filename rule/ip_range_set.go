@@ -0,0 +1,129 @@
+package rule
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// IPRangeSet is a set of IPv4 and IPv6 addresses stored as sorted and merged ranges.
+type IPRangeSet struct {
+	ranges []ipRange
+}
+
+type ipRange struct {
+	start netip.Addr
+	end   netip.Addr
+}
+
+// NewIPRangeSet creates a set from CIDRs or IP addresses.
+func NewIPRangeSet(cidrs []string) (*IPRangeSet, error) {
+	ranges := make([]ipRange, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := parseIPPrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("parse cidr %s: %w", cidr, err)
+		}
+		ranges = append(ranges, ipRange{start: prefix.Addr(), end: lastAddr(prefix)})
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start.Less(ranges[j].start)
+	})
+	merged := []ipRange{}
+	for _, r := range ranges {
+		if len(merged) > 0 {
+			last := &merged[len(merged)-1]
+			next := last.end.Next()
+			if r.start.Compare(last.end) <= 0 || (next.IsValid() && next == r.start) {
+				if last.end.Less(r.end) {
+					last.end = r.end
+				}
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	return &IPRangeSet{
+		ranges: merged,
+	}, nil
+}
+
+func (s *IPRangeSet) Contains(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	i := sort.Search(len(s.ranges), func(i int) bool {
+		return ip.Less(s.ranges[i].start)
+	})
+	if i == 0 {
+		return false
+	}
+	r := s.ranges[i-1]
+	return r.start.BitLen() == ip.BitLen() && ip.Compare(r.end) <= 0
+}
+
+func parseIPPrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Addr().As16()
+	offset := 0
+	if prefix.Addr().Is4() {
+		offset = 96
+	}
+	for bit := offset + prefix.Bits(); bit < 128; bit++ {
+		addr[bit/8] |= 1 << (7 - bit%8)
+	}
+	last := netip.AddrFrom16(addr)
+	if prefix.Addr().Is4() {
+		return last.Unmap()
+	}
+	return last
+}
+
+func LoadIPListFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+	return ParseIPList(f)
+}
+
+// ParseIPList reads IP addresses or CIDRs, one per line.
+// Text after ";" or "#" is ignored as a comment, as in Spamhaus DROP lists.
+func ParseIPList(r io.Reader) ([]string, error) {
+	cidrs := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexAny(line, ";#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		cidrs = append(cidrs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan lines: %w", err)
+	}
+	return cidrs, nil
+}
@@ -0,0 +1,104 @@
+package rule_test
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestIPRangeSet_Contains(t *testing.T) {
+	set, err := rule.NewIPRangeSet([]string{
+		"192.0.2.0/25",
+		"192.0.2.128/25",
+		"198.51.100.10",
+		"10.0.0.0/8",
+		"10.1.0.0/16",
+		"2001:db8::/32",
+		"0.0.0.0/32",
+		"255.255.255.255/32",
+	})
+	if err != nil {
+		t.Fatalf("create ip range set: %v", err)
+	}
+
+	cases := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "192.0.2.0", want: true},
+		{ip: "192.0.2.255", want: true},
+		{ip: "192.0.3.0", want: false},
+		{ip: "198.51.100.10", want: true},
+		{ip: "198.51.100.11", want: false},
+		{ip: "10.255.255.255", want: true},
+		{ip: "11.0.0.0", want: false},
+		{ip: "::ffff:10.0.0.1", want: true},
+		{ip: "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff", want: true},
+		{ip: "2001:db9::", want: false},
+		{ip: "::", want: false},
+		{ip: "0.0.0.0", want: true},
+		{ip: "255.255.255.255", want: true},
+		{ip: "::ffff:ffff", want: false},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := set.Contains(netip.MustParseAddr(tt.ip)); tt.want != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewIPRangeSet_InvalidCIDR(t *testing.T) {
+	for _, cidr := range []string{"", "192.0.2.0/33", "example.com"} {
+		if _, err := rule.NewIPRangeSet([]string{cidr}); err == nil {
+			t.Errorf("expected error for %q, but got nil", cidr)
+		}
+	}
+}
+
+func TestParseIPList(t *testing.T) {
+	body := `; Spamhaus DROP List 2024/01/01 - (c) 2024 The Spamhaus Project SLR
+; Last-Modified: Mon, 1 Jan 2024 00:00:00 GMT
+1.10.16.0/20 ; SBL256894
+2001:db8::/32 ; SBL000000
+# comment
+192.0.2.1
+
+`
+	got, err := rule.ParseIPList(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"1.10.16.0/20", "2001:db8::/32", "192.0.2.1"}
+	if len(want) != len(got) {
+		t.Fatalf("unexpected result: want %v, but got %v", want, got)
+	}
+	for i := range want {
+		if want[i] != got[i] {
+			t.Errorf("unexpected result: want %v, but got %v", want, got)
+		}
+	}
+}
+
+func BenchmarkIPRangeSet_Contains(b *testing.B) {
+	cidrs := make([]string, 0, 50000)
+	for i := 0; i < cap(cidrs); i++ {
+		// leaves gaps so that ranges are not merged
+		cidrs = append(cidrs, fmt.Sprintf("%d.%d.%d.0/24", 1+i/32768, (i/128)%256, (i%128)*2))
+	}
+	set, err := rule.NewIPRangeSet(cidrs)
+	if err != nil {
+		b.Fatalf("create ip range set: %v", err)
+	}
+	ip := netip.MustParseAddr("1.100.100.100")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set.Contains(ip)
+	}
+}
@@ -0,0 +1,94 @@
+package rule
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Links returns the links in the content and the link attachments of the object.
+// Links to mentioned users and hashtags are excluded.
+func (o *ActivityObject) Links() []*url.URL {
+	excluded := map[string]struct{}{}
+	for _, tag := range o.Tag {
+		if tag.Type == "Mention" || tag.Type == "Hashtag" {
+			excluded[tag.HRef] = struct{}{}
+		}
+	}
+
+	links := []*url.URL{}
+	appendLink := func(href string) {
+		if _, ok := excluded[href]; ok {
+			return
+		}
+		u, err := url.Parse(strings.TrimSpace(href))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return
+		}
+		links = append(links, u)
+	}
+
+	for _, href := range extractAnchorHRefs(o.Content) {
+		appendLink(href)
+	}
+	for _, attachment := range o.Attachment {
+		if attachment.Type == "Link" || attachment.Type == "Page" {
+			appendLink(attachment.URL)
+		}
+	}
+	return links
+}
+
+// extractAnchorHRefs returns hrefs of anchors except for mentions and hashtags.
+func extractAnchorHRefs(content string) []string {
+	if content == "" {
+		return nil
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(content), &html.Node{Type: html.ElementNode, DataAtom: atom.Body, Data: "body"})
+	if err != nil {
+		return nil
+	}
+
+	hrefs := []string{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			if href, ok := anchorHRef(n); ok {
+				hrefs = append(hrefs, href)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	return hrefs
+}
+
+func anchorHRef(n *html.Node) (string, bool) {
+	href := ""
+	found := false
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "href":
+			href, found = attr.Val, true
+		case "class":
+			for _, class := range strings.Fields(attr.Val) {
+				if class == "mention" || class == "hashtag" {
+					return "", false
+				}
+			}
+		case "rel":
+			for _, rel := range strings.Fields(attr.Val) {
+				if rel == "tag" {
+					return "", false
+				}
+			}
+		}
+	}
+	return href, found
+}
@@ -0,0 +1,88 @@
+package rule
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// URLShortenerDomains are domains of well-known URL shortening services.
+var URLShortenerDomains = []string{
+	"bit.ly", "bit.do", "buff.ly", "cutt.ly", "dlvr.it", "goo.gl", "is.gd", "lnkd.in",
+	"ow.ly", "rb.gy", "rebrand.ly", "s.id", "shorturl.at", "t.co", "t.ly", "tiny.cc",
+	"tinyurl.com", "trib.al", "v.gd", "x.gd",
+}
+
+type linkCountMatcher struct {
+	condition CountCondition
+}
+
+func NewLinkCountMatcher(condition CountCondition) (*linkCountMatcher, error) {
+	if err := condition.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid condition: %w", err)
+	}
+	return &linkCountMatcher{
+		condition: condition,
+	}, nil
+}
+
+func (m *linkCountMatcher) Test(req *ProxyRequest) (bool, error) {
+	links, err := noteLinks(req)
+	if err != nil {
+		return false, err
+	}
+	return m.condition.Match(len(links)), nil
+}
+
+type linkDomainMatcher struct {
+	domains *DomainSet
+}
+
+func NewLinkDomainMatcher(domains *DomainSet) (*linkDomainMatcher, error) {
+	if domains == nil {
+		return nil, fmt.Errorf("nil domain set")
+	}
+	return &linkDomainMatcher{
+		domains: domains,
+	}, nil
+}
+
+func (m *linkDomainMatcher) Test(req *ProxyRequest) (bool, error) {
+	links, err := noteLinks(req)
+	if err != nil {
+		return false, err
+	}
+	for _, link := range links {
+		if m.domains.Contains(link.Hostname()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type linkLookalikeMatcher struct{}
+
+func NewLinkLookalikeMatcher() (*linkLookalikeMatcher, error) {
+	return &linkLookalikeMatcher{}, nil
+}
+
+func (m *linkLookalikeMatcher) Test(req *ProxyRequest) (bool, error) {
+	links, err := noteLinks(req)
+	if err != nil {
+		return false, err
+	}
+	for _, link := range links {
+		if isLookalikeHost(link.Hostname()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// noteLinks returns the links of the object posted to an inbox.
+func noteLinks(req *ProxyRequest) ([]*url.URL, error) {
+	object, err := postedObject(req)
+	if err != nil || object == nil {
+		return nil, err
+	}
+	return req.Links()
+}
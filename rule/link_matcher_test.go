@@ -0,0 +1,148 @@
+package rule_test
+
+import (
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestLinkCountMatcher_Test(t *testing.T) {
+	cases := []struct {
+		name       string
+		path       string
+		content    string
+		moreThan   int
+		wantResult bool
+	}{
+		{
+			name:       "more links than threshold",
+			path:       "/inbox",
+			content:    `<a href="https://a.example/">a</a> <a href="https://b.example/">b</a>`,
+			moreThan:   1,
+			wantResult: true,
+		},
+		{
+			name:       "links equal to threshold",
+			path:       "/inbox",
+			content:    `<a href="https://a.example/">a</a> <a href="https://b.example/tags/x" rel="tag">#x</a>`,
+			moreThan:   1,
+			wantResult: false,
+		},
+		{
+			name:       "request other than inbox",
+			path:       "/api/v1/statuses",
+			content:    `<a href="https://a.example/">a</a>`,
+			moreThan:   0,
+			wantResult: false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := rule.NewLinkCountMatcher(rule.CountCondition{GreaterThan: &tt.moreThan})
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			got, err := m.Test(newObjectRequest(t, tt.path, "Create", map[string]any{"type": "Note", "content": tt.content}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestLinkDomainMatcher_Test(t *testing.T) {
+	domains, err := rule.NewDomainSet(append([]string{"*.spam.example", "例え.jp"}, rule.URLShortenerDomains...))
+	if err != nil {
+		t.Fatalf("create domain set: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		content    string
+		wantResult bool
+	}{
+		{
+			name:       "link to listed domain",
+			content:    `<a href="https://example.com/">ok</a> <a href="https://www.spam.example/offer">offer</a>`,
+			wantResult: true,
+		},
+		{
+			name:       "link to url shortener",
+			content:    `<a href="https://bit.ly/abcdef">link</a>`,
+			wantResult: true,
+		},
+		{
+			name:       "link to internationalized domain in punycode",
+			content:    `<a href="https://xn--r8jz45g.jp/">link</a>`,
+			wantResult: true,
+		},
+		{
+			name:       "link to internationalized domain",
+			content:    `<a href="https://例え.jp/">link</a>`,
+			wantResult: true,
+		},
+		{
+			name:       "link to other domain",
+			content:    `<a href="https://example.com/">ok</a>`,
+			wantResult: false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := rule.NewLinkDomainMatcher(domains)
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			got, err := m.Test(newObjectRequest(t, "/inbox", "Create", map[string]any{"type": "Note", "content": tt.content}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestLinkLookalikeMatcher_Test(t *testing.T) {
+	cases := []struct {
+		name       string
+		host       string
+		wantResult bool
+	}{
+		{name: "ascii domain", host: "paypal.com", wantResult: false},
+		{name: "cyrillic letter mixed into latin", host: "pаypal.com", wantResult: true},
+		{name: "cyrillic letter mixed into latin in punycode", host: "xn--pypal-4ve.com", wantResult: true},
+		{name: "cyrillic letters looking like latin", host: "аррӏе.com", wantResult: true},
+		{name: "latin lookalike letter", host: "ɡoogle.com", wantResult: true},
+		{name: "cyrillic small capital letters", host: "вкмнт.com", wantResult: true},
+		{name: "greek tau", host: "τοκ.com", wantResult: true},
+		{name: "latin small capital and script l", host: "ʟℓ.com", wantResult: true},
+		{name: "latin small capital i", host: "Ɪɪ.com", wantResult: true},
+		{name: "lisu letter i", host: "ꓲꓲ.com", wantResult: true},
+		{name: "latin with diacritics", host: "bücher.de", wantResult: false},
+		{name: "cyrillic domain", host: "пример.рф", wantResult: false},
+		{name: "japanese domain", host: "例え.jp", wantResult: false},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := rule.NewLinkLookalikeMatcher()
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			got, err := m.Test(newObjectRequest(t, "/inbox", "Create", map[string]any{"type": "Note", "content": `<a href="https://` + tt.host + `/">link</a>`}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package rule_test
+
+import (
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestActivityObject_Links(t *testing.T) {
+	cases := []struct {
+		name   string
+		object rule.ActivityObject
+		want   []string
+	}{
+		{
+			name: "mastodon",
+			object: rule.ActivityObject{
+				Content: `<p><span class="h-card" translate="no"><a href="https://example.com/@alice" class="u-url mention">@<span>alice</span></a></span> check <a href="https://spam.example/offer?id=1" target="_blank" rel="nofollow noopener noreferrer"><span class="invisible">https://</span><span class="">spam.example/offer?id=1</span></a> <a href="https://example.com/tags/sale" class="mention hashtag" rel="tag">#<span>sale</span></a></p>`,
+				Tag: []rule.ActivityTag{
+					{Type: "Mention", HRef: "https://example.com/users/alice", Name: "@alice@example.com"},
+					{Type: "Hashtag", HRef: "https://example.com/tags/sale", Name: "#sale"},
+				},
+			},
+			want: []string{"https://spam.example/offer?id=1"},
+		},
+		{
+			name: "pleroma",
+			object: rule.ActivityObject{
+				Content: `<span class="h-card"><a class="u-url mention" data-user="1" href="https://example.com/users/alice" rel="ugc">@<span>alice</span></a></span> <a class="hashtag" data-tag="sale" href="https://example.com/tag/sale" rel="tag ugc">#sale</a> <a href="http://spam.example/">http://spam.example/</a>`,
+			},
+			want: []string{"http://spam.example/"},
+		},
+		{
+			name: "mention without class",
+			object: rule.ActivityObject{
+				Content: `<a href="https://example.com/users/alice">@alice</a> <a href="https://spam.example/">link</a>`,
+				Tag:     []rule.ActivityTag{{Type: "Mention", HRef: "https://example.com/users/alice"}},
+			},
+			want: []string{"https://spam.example/"},
+		},
+		{
+			name: "link attachment",
+			object: rule.ActivityObject{
+				Content: `<p>hello</p>`,
+				Attachment: []rule.ActivityAttachment{
+					{Type: "Document", MediaType: "image/png", URL: "https://example.com/media/1.png"},
+					{Type: "Link", URL: "https://spam.example/page"},
+				},
+			},
+			want: []string{"https://spam.example/page"},
+		},
+		{
+			name: "non-http links",
+			object: rule.ActivityObject{
+				Content: `<a href="mailto:alice@example.com">mail</a> <a href="javascript:alert(1)">js</a> <a href="/relative">relative</a> <a>no href</a>`,
+			},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.object.Links()
+			if len(tt.want) != len(got) {
+				t.Fatalf("unexpected result: want %v, but got %v", tt.want, got)
+			}
+			for i := range tt.want {
+				if tt.want[i] != got[i].String() {
+					t.Errorf("unexpected result: want %v, but got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package rule
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// lookalikeScripts are scripts including letters which look like Latin letters.
+var lookalikeScripts = []*unicode.RangeTable{unicode.Latin, unicode.Cyrillic, unicode.Greek, unicode.Armenian, unicode.Cherokee}
+
+// isLookalikeHost reports whether a label of the host mixes Latin-like scripts,
+// or consists of letters which look like ASCII letters.
+func isLookalikeHost(host string) bool {
+	unicodeHost, err := idna.ToUnicode(strings.ToLower(host))
+	if err != nil {
+		// undecodable punycode is suspicious
+		return strings.Contains(host, "xn--")
+	}
+	for _, label := range strings.Split(unicodeHost, ".") {
+		if isLookalikeLabel(label) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLookalikeLabel(label string) bool {
+	scripts := map[*unicode.RangeTable]struct{}{}
+	nonASCII := false
+	allConfusable := true
+	for _, r := range label {
+		if r < unicode.MaxASCII {
+			if unicode.IsLetter(r) {
+				scripts[unicode.Latin] = struct{}{}
+			}
+			continue
+		}
+		nonASCII = true
+		if _, ok := latinConfusables[r]; !ok {
+			allConfusable = false
+		}
+		for _, script := range lookalikeScripts {
+			if unicode.Is(script, r) {
+				scripts[script] = struct{}{}
+				break
+			}
+		}
+	}
+	if !nonASCII {
+		return false
+	}
+	return len(scripts) > 1 || allConfusable
+}
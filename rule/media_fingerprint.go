@@ -0,0 +1,119 @@
+package rule
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type MediaFingerprintKind string
+
+const (
+	MEDIA_FINGERPRINT_BLURHASH   MediaFingerprintKind = "blurhash"
+	MEDIA_FINGERPRINT_URL_SHA256 MediaFingerprintKind = "url-sha256"
+)
+
+// MediaFingerprint identifies a known media attachment.
+// URLs are stored as SHA-256 digests so that lists do not carry links to the media.
+type MediaFingerprint struct {
+	Kind  MediaFingerprintKind
+	Value string
+}
+
+func (f MediaFingerprint) String() string {
+	return string(f.Kind) + " " + f.Value
+}
+
+// Validate checks that the value is well-formed for the kind.
+func (f MediaFingerprint) Validate() error {
+	switch f.Kind {
+	case MEDIA_FINGERPRINT_BLURHASH:
+		if _, err := decodeBlurhash(f.Value); err != nil {
+			return fmt.Errorf("invalid blurhash %q: %w", f.Value, err)
+		}
+	case MEDIA_FINGERPRINT_URL_SHA256:
+		if digest, err := hex.DecodeString(f.Value); err != nil || len(digest) != sha256.Size || f.Value != strings.ToLower(f.Value) {
+			return fmt.Errorf("invalid sha256 digest: %s", f.Value)
+		}
+	default:
+		return fmt.Errorf("unexpected fingerprint kind: %s", f.Kind)
+	}
+	return nil
+}
+
+// AttachmentFingerprints returns the fingerprints of the attachment.
+func AttachmentFingerprints(attachment ActivityAttachment) []MediaFingerprint {
+	fingerprints := []MediaFingerprint{}
+	if attachment.Blurhash != "" {
+		fingerprints = append(fingerprints, MediaFingerprint{Kind: MEDIA_FINGERPRINT_BLURHASH, Value: attachment.Blurhash})
+	}
+	if attachment.URL != "" {
+		fingerprints = append(fingerprints, MediaFingerprint{Kind: MEDIA_FINGERPRINT_URL_SHA256, Value: URLDigest(attachment.URL)})
+	}
+	return fingerprints
+}
+
+// ActivityFingerprints returns the fingerprints of the media attached to the object of the activity.
+// Blurhashes are taken as sent by the remote server, so callers should Validate them before storing.
+func ActivityFingerprints(activity *Activity) []MediaFingerprint {
+	fingerprints := []MediaFingerprint{}
+	if activity.Object == nil {
+		return fingerprints
+	}
+	for _, attachment := range activity.Object.Attachment {
+		if isMediaAttachment(attachment) {
+			fingerprints = append(fingerprints, AttachmentFingerprints(attachment)...)
+		}
+	}
+	return fingerprints
+}
+
+func URLDigest(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+func LoadMediaFingerprintFile(path string) ([]MediaFingerprint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+	return ParseMediaFingerprints(f)
+}
+
+// ParseMediaFingerprints reads a list with a kind and a value separated by spaces per line.
+// Lines starting with # are comments, and the text after the value is ignored.
+// # is not treated as a comment in the middle of a line because it is used in blurhashes.
+func ParseMediaFingerprints(r io.Reader) ([]MediaFingerprint, error) {
+	fingerprints := []MediaFingerprint{}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: missing value", lineNumber)
+		}
+		fingerprint := MediaFingerprint{Kind: MediaFingerprintKind(strings.ToLower(fields[0])), Value: fields[1]}
+		if fingerprint.Kind == MEDIA_FINGERPRINT_URL_SHA256 {
+			fingerprint.Value = strings.ToLower(fingerprint.Value)
+		}
+		if err := fingerprint.Validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan lines: %w", err)
+	}
+	return fingerprints, nil
+}
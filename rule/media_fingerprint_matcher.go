@@ -0,0 +1,62 @@
+package rule
+
+import "fmt"
+
+type mediaFingerprintMatcher struct {
+	blurhashes []blurhashComponents
+	urlDigests map[string]struct{}
+	// distance is the number of blurhash values allowed to differ.
+	distance int
+}
+
+func NewMediaFingerprintMatcher(fingerprints []MediaFingerprint, distance int) (*mediaFingerprintMatcher, error) {
+	if len(fingerprints) == 0 {
+		return nil, fmt.Errorf("empty fingerprints")
+	}
+	if distance < 0 {
+		return nil, fmt.Errorf("invalid distance: %d", distance)
+	}
+	m := &mediaFingerprintMatcher{
+		urlDigests: map[string]struct{}{},
+		distance:   distance,
+	}
+	for _, fingerprint := range fingerprints {
+		switch fingerprint.Kind {
+		case MEDIA_FINGERPRINT_BLURHASH:
+			components, err := decodeBlurhash(fingerprint.Value)
+			if err != nil {
+				return nil, fmt.Errorf("decode blurhash: %w", err)
+			}
+			m.blurhashes = append(m.blurhashes, components)
+		case MEDIA_FINGERPRINT_URL_SHA256:
+			m.urlDigests[fingerprint.Value] = struct{}{}
+		default:
+			return nil, fmt.Errorf("unexpected fingerprint kind: %s", fingerprint.Kind)
+		}
+	}
+	return m, nil
+}
+
+func (m *mediaFingerprintMatcher) Test(req *ProxyRequest) (bool, error) {
+	return anyMediaAttachment(req, func(attachment ActivityAttachment) bool {
+		if attachment.URL != "" && len(m.urlDigests) > 0 {
+			if _, ok := m.urlDigests[URLDigest(attachment.URL)]; ok {
+				return true
+			}
+		}
+		if attachment.Blurhash == "" || len(m.blurhashes) == 0 {
+			return false
+		}
+		components, err := decodeBlurhash(attachment.Blurhash)
+		if err != nil {
+			// broken blurhashes sent by remote servers are ignored
+			return false
+		}
+		for _, known := range m.blurhashes {
+			if d := components.distance(known); d >= 0 && d <= m.distance {
+				return true
+			}
+		}
+		return false
+	})
+}
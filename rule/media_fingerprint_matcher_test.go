@@ -0,0 +1,80 @@
+package rule_test
+
+import (
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestMediaFingerprintMatcher_Test(t *testing.T) {
+	fingerprints := []rule.MediaFingerprint{
+		{Kind: rule.MEDIA_FINGERPRINT_BLURHASH, Value: "LNJRyVof~qj[ayayj[j[%Mj[WBay"},
+		{Kind: rule.MEDIA_FINGERPRINT_URL_SHA256, Value: rule.URLDigest("https://files.spam.example/media/ad_banner.png")},
+	}
+
+	cases := []struct {
+		name       string
+		distance   int
+		attachment any
+		wantResult bool
+	}{
+		{
+			name:       "same blurhash",
+			distance:   0,
+			attachment: map[string]any{"type": "Image", "url": "https://example.com/a.png", "blurhash": "LNJRyVof~qj[ayayj[j[%Mj[WBay"},
+			wantResult: true,
+		},
+		{
+			name:       "similar blurhash within distance",
+			distance:   1,
+			attachment: map[string]any{"type": "Image", "url": "https://example.com/a.png", "blurhash": "LNJRyVof~qj[ayayj[j[%Mj[WBaz"},
+			wantResult: true,
+		},
+		{
+			name:       "similar blurhash beyond distance",
+			distance:   0,
+			attachment: map[string]any{"type": "Image", "url": "https://example.com/a.png", "blurhash": "LNJRyVof~qj[ayayj[j[%Mj[WBaz"},
+			wantResult: false,
+		},
+		{
+			name:       "blurhash with different number of components",
+			distance:   64,
+			attachment: map[string]any{"type": "Image", "url": "https://example.com/a.png", "blurhash": "UBL_:rOpGG-oBUNG,qRj2so|=eE1w^n4S5NH"},
+			wantResult: false,
+		},
+		{
+			name:       "broken blurhash",
+			distance:   64,
+			attachment: map[string]any{"type": "Image", "url": "https://example.com/a.png", "blurhash": "broken"},
+			wantResult: false,
+		},
+		{
+			name:       "known url",
+			distance:   0,
+			attachment: map[string]any{"type": "Image", "url": "https://files.spam.example/media/ad_banner.png"},
+			wantResult: true,
+		},
+		{
+			name:       "unknown url without blurhash",
+			distance:   0,
+			attachment: map[string]any{"type": "Image", "url": "https://example.com/a.png"},
+			wantResult: false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := rule.NewMediaFingerprintMatcher(fingerprints, tt.distance)
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			got, err := m.Test(newObjectRequest(t, "/inbox", "Create", map[string]any{"type": "Note", "content": "", "attachment": tt.attachment}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
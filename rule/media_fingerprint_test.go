@@ -0,0 +1,95 @@
+package rule_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestParseMediaFingerprints(t *testing.T) {
+	digest := rule.URLDigest("https://files.spam.example/media/ad_banner.png")
+	cases := []struct {
+		name    string
+		body    string
+		want    []rule.MediaFingerprint
+		wantErr bool
+	}{
+		{
+			name: "blurhashes and url digests with comments",
+			body: "# spam wave\n\nblurhash LNJRyVof~qj[ayayj[j[%Mj[WBay https://example.com/activity\nURL-SHA256 " + strings.ToUpper(digest) + "\n",
+			want: []rule.MediaFingerprint{
+				{Kind: rule.MEDIA_FINGERPRINT_BLURHASH, Value: "LNJRyVof~qj[ayayj[j[%Mj[WBay"},
+				{Kind: rule.MEDIA_FINGERPRINT_URL_SHA256, Value: digest},
+			},
+		},
+		{
+			name:    "invalid blurhash",
+			body:    "blurhash LNJRyVof\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid digest",
+			body:    "url-sha256 abcdef\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown kind",
+			body:    "md5 d41d8cd98f00b204e9800998ecf8427e\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing value",
+			body:    "blurhash\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rule.ParseMediaFingerprints(strings.NewReader(tt.body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("unexpected result: want %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestActivityFingerprints(t *testing.T) {
+	activity, err := rule.ParseActivity([]byte(`{
+		"type": "Create",
+		"object": {
+			"type": "Note",
+			"attachment": [
+				{"type": "Document", "url": "https://files.spam.example/a.png", "blurhash": "LNJRyVof~qj[ayayj[j[%Mj[WBay"},
+				{"type": "Document", "url": "https://files.spam.example/b.png"},
+				{"type": "PropertyValue", "name": "Website", "value": "https://example.com/"}
+			]
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("parse activity: %v", err)
+	}
+	want := []string{
+		"blurhash LNJRyVof~qj[ayayj[j[%Mj[WBay",
+		"url-sha256 " + rule.URLDigest("https://files.spam.example/a.png"),
+		"url-sha256 " + rule.URLDigest("https://files.spam.example/b.png"),
+	}
+	got := []string{}
+	for _, fingerprint := range rule.ActivityFingerprints(activity) {
+		got = append(got, fingerprint.String())
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected result: want %v, but got %v", want, got)
+	}
+}
@@ -65,6 +65,35 @@ func TestMentionCountMatcher(t *testing.T) {
 		}
 	}
 	`
+	testSingleTagBody := `
+	{
+		"type": "Create",
+		"actor": "https://example.com/users/test",
+		"to": "https://www.w3.org/ns/activitystreams#Public",
+		"object": {
+			"type": "Note",
+			"content": "",
+			"tag": {
+				"type": "Mention",
+				"href": "https://example.com/users/test1",
+				"name": "@test1@example.com"
+			}
+		}
+	}`
+	testUpdateQuestionBody := `
+	{
+		"type": "Update",
+		"actor": "https://example.com/users/test",
+		"object": {
+			"type": "Question",
+			"content": "",
+			"tag": [
+				{"type": "Mention", "href": "https://example.com/users/test1"},
+				{"type": "Mention", "href": "https://example.com/users/test2"}
+			],
+			"oneOf": [{"type": "Note", "name": "yes"}, {"type": "Note", "name": "no"}]
+		}
+	}`
 	cases := []struct {
 		name          string
 		moreThanCount int
@@ -89,6 +118,18 @@ func TestMentionCountMatcher(t *testing.T) {
 			requestBody:   testCreateBody,
 			wantResult:    false,
 		},
+		{
+			name:          "single mention tag is not an array",
+			moreThanCount: 0,
+			requestBody:   testSingleTagBody,
+			wantResult:    true,
+		},
+		{
+			name:          "edited poll",
+			moreThanCount: 1,
+			requestBody:   testUpdateQuestionBody,
+			wantResult:    true,
+		},
 		{
 			name:          "not Create Note activity",
 			moreThanCount: 1,
@@ -99,7 +140,7 @@ func TestMentionCountMatcher(t *testing.T) {
 
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
-			m, err := rule.NewMentionCountMatcher(tt.moreThanCount)
+			m, err := rule.NewMentionCountMatcher(rule.CountCondition{GreaterThan: &tt.moreThanCount})
 			if err != nil {
 				t.Fatalf("create matcher: %v", err)
 			}
@@ -0,0 +1,116 @@
+package rule
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// publicAddresses are the representations of the public collection.
+var publicAddresses = []string{"https://www.w3.org/ns/activitystreams#Public", "as:Public", "Public"}
+
+type mentionTargets struct {
+	local   int
+	remote  int
+	domains int
+}
+
+// mentionTargetMatcher matches when the count of the mention targets satisfies the condition.
+type mentionTargetMatcher struct {
+	localDomains *DomainSet
+	count        func(targets mentionTargets) int
+	condition    CountCondition
+}
+
+// NewLocalMentionCountMatcher creates a matcher on the number of mentioned users in the local domains.
+func NewLocalMentionCountMatcher(localDomains *DomainSet, condition CountCondition) (*mentionTargetMatcher, error) {
+	if localDomains == nil {
+		return nil, fmt.Errorf("nil local domains")
+	}
+	return newMentionTargetMatcher(localDomains, func(targets mentionTargets) int { return targets.local }, condition)
+}
+
+// NewRemoteMentionCountMatcher creates a matcher on the number of mentioned users outside the local domains.
+func NewRemoteMentionCountMatcher(localDomains *DomainSet, condition CountCondition) (*mentionTargetMatcher, error) {
+	if localDomains == nil {
+		return nil, fmt.Errorf("nil local domains")
+	}
+	return newMentionTargetMatcher(localDomains, func(targets mentionTargets) int { return targets.remote }, condition)
+}
+
+// NewMentionDomainCountMatcher creates a matcher on the number of distinct domains of mentioned users.
+func NewMentionDomainCountMatcher(condition CountCondition) (*mentionTargetMatcher, error) {
+	return newMentionTargetMatcher(nil, func(targets mentionTargets) int { return targets.domains }, condition)
+}
+
+func newMentionTargetMatcher(localDomains *DomainSet, count func(targets mentionTargets) int, condition CountCondition) (*mentionTargetMatcher, error) {
+	if err := condition.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid condition: %w", err)
+	}
+	return &mentionTargetMatcher{
+		localDomains: localDomains,
+		count:        count,
+		condition:    condition,
+	}, nil
+}
+
+func (m *mentionTargetMatcher) Test(req *ProxyRequest) (bool, error) {
+	object, err := postedObject(req)
+	if err != nil || object == nil {
+		return false, err
+	}
+	activity, err := req.Activity()
+	if err != nil {
+		return false, fmt.Errorf("parse activity: %w", err)
+	}
+	return m.condition.Match(m.count(m.classify(activity, object))), nil
+}
+
+// classify counts the users mentioned by Mention tags or addressed in to and cc.
+// The public collection and followers collections are not users and are excluded.
+func (m *mentionTargetMatcher) classify(activity *Activity, object *ActivityObject) mentionTargets {
+	addresses := []string{}
+	for _, tag := range object.Tag {
+		if tag.Type == "Mention" {
+			addresses = append(addresses, tag.HRef)
+		}
+	}
+	for _, recipients := range [][]string{activity.To, activity.Cc, object.To, object.Cc} {
+		addresses = append(addresses, recipients...)
+	}
+
+	targets := mentionTargets{}
+	seenUsers := map[string]struct{}{}
+	seenDomains := map[string]struct{}{}
+	for _, address := range addresses {
+		if _, ok := seenUsers[address]; ok || isCollectionAddress(address) {
+			continue
+		}
+		u, err := url.Parse(address)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		seenUsers[address] = struct{}{}
+
+		host := normalizeDomain(u.Hostname())
+		if _, ok := seenDomains[host]; !ok {
+			seenDomains[host] = struct{}{}
+			targets.domains++
+		}
+		if m.localDomains != nil && m.localDomains.Contains(host) {
+			targets.local++
+		} else {
+			targets.remote++
+		}
+	}
+	return targets
+}
+
+func isCollectionAddress(address string) bool {
+	for _, public := range publicAddresses {
+		if address == public {
+			return true
+		}
+	}
+	return strings.HasSuffix(address, "/followers")
+}
@@ -0,0 +1,96 @@
+package rule_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestMentionTargetMatchers_Test(t *testing.T) {
+	body := `
+	{
+		"type": "Create",
+		"actor": "https://remote.example/users/spammer",
+		"to": ["https://www.w3.org/ns/activitystreams#Public"],
+		"cc": [
+			"https://remote.example/users/spammer/followers",
+			"https://local.example/users/alice",
+			"https://local.example/users/bob",
+			"https://other.example/users/carol"
+		],
+		"object": {
+			"type": "Note",
+			"content": "",
+			"to": "as:Public",
+			"cc": ["https://local.example/users/alice", "https://SUB.Local.example/users/dave"],
+			"tag": [
+				{"type": "Mention", "href": "https://local.example/users/alice"},
+				{"type": "Mention", "href": "https://local.example/users/bob"},
+				{"type": "Mention", "href": "https://other.example/users/carol"},
+				{"type": "Mention", "href": "https://third.example/users/erin"},
+				{"type": "Hashtag", "href": "https://fourth.example/tags/spam"}
+			]
+		}
+	}`
+	localDomains, err := rule.NewDomainSet([]string{"local.example", "*.local.example"})
+	if err != nil {
+		t.Fatalf("create domain set: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		newMatcher func() (rule.RuleMatcher, error)
+		wantResult bool
+	}{
+		{
+			name: "local mentions",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewLocalMentionCountMatcher(localDomains, rule.CountCondition{Equal: intPtr(3)})
+			},
+			wantResult: true,
+		},
+		{
+			name: "remote mentions",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewRemoteMentionCountMatcher(localDomains, rule.CountCondition{Equal: intPtr(2)})
+			},
+			wantResult: true,
+		},
+		{
+			name: "mentioned domains",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewMentionDomainCountMatcher(rule.CountCondition{Equal: intPtr(4)})
+			},
+			wantResult: true,
+		},
+		{
+			name: "local mentions below threshold",
+			newMatcher: func() (rule.RuleMatcher, error) {
+				return rule.NewLocalMentionCountMatcher(localDomains, rule.CountCondition{GreaterThan: intPtr(3)})
+			},
+			wantResult: false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := tt.newMatcher()
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			req, err := http.NewRequest("POST", "/inbox", bytes.NewBufferString(body))
+			if err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+			got, err := m.Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
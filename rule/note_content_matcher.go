@@ -1,18 +1,22 @@
 package rule
 
-import (
-	"encoding/json"
-	"fmt"
-	"strings"
-)
+import "fmt"
 
 type noteContentMatcher struct {
-	pattern string
+	pattern StringPattern
 }
 
 func NewNoteContentMatcher(pattern string) (*noteContentMatcher, error) {
-	if len(pattern) == 0 {
-		return nil, fmt.Errorf("empty pattern text")
+	containsPattern, err := NewContainsPattern(pattern, false)
+	if err != nil {
+		return nil, err
+	}
+	return NewNoteContentPatternMatcher(containsPattern)
+}
+
+func NewNoteContentPatternMatcher(pattern StringPattern) (*noteContentMatcher, error) {
+	if pattern == nil {
+		return nil, fmt.Errorf("nil pattern")
 	}
 	return &noteContentMatcher{
 		pattern: pattern,
@@ -20,38 +24,10 @@ func NewNoteContentMatcher(pattern string) (*noteContentMatcher, error) {
 }
 
 func (m *noteContentMatcher) Test(req *ProxyRequest) (bool, error) {
-	if !strings.HasSuffix(req.Request.URL.Path, "/inbox") {
-		return false, nil
-	}
-
-	body, err := req.Body()
-	if err != nil {
-		return false, fmt.Errorf("fetch body: %w", err)
-	}
-
-	payload := struct {
-		Type string `json:"type"`
-	}{}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		return false, fmt.Errorf("unmarshal json: %w", err)
-	}
-
-	if payload.Type != "Create" {
-		return false, nil
-	}
-
-	createActivityPayload := struct {
-		Object struct {
-			Type    string `json:"type"`
-			Content string `json:"content"`
-		} `json:"object"`
-	}{}
-
-	if err := json.Unmarshal(body, &createActivityPayload); err != nil {
-		return false, fmt.Errorf("unmarshal json: %w", err)
+	object, err := postedObject(req)
+	if err != nil || object == nil {
+		return false, err
 	}
 
-	return createActivityPayload.Object.Type == "Note" &&
-			strings.Contains(createActivityPayload.Object.Content, m.pattern),
-		nil
+	return m.pattern.Match(object.Content), nil
 }
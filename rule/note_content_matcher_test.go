@@ -0,0 +1,69 @@
+package rule_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestNoteContentMatcher_Test(t *testing.T) {
+	cases := []struct {
+		name       string
+		body       string
+		wantResult bool
+	}{
+		{
+			name:       "created note",
+			body:       `{"type": "Create", "actor": "https://example.com/users/bob", "object": {"type": "Note", "content": "<p>spam</p>"}}`,
+			wantResult: true,
+		},
+		{
+			name:       "edited note",
+			body:       `{"type": "Update", "actor": "https://example.com/users/bob", "object": {"type": "Note", "content": "<p>spam</p>"}}`,
+			wantResult: true,
+		},
+		{
+			name:       "poll",
+			body:       `{"type": "Create", "actor": "https://example.com/users/bob", "object": {"type": "Question", "content": "<p>spam</p>"}}`,
+			wantResult: true,
+		},
+		{
+			name:       "article",
+			body:       `{"type": "Create", "actor": "https://example.com/users/bob", "object": {"type": "Article", "content": "<p>spam</p>"}}`,
+			wantResult: true,
+		},
+		{
+			name:       "content does not contain pattern",
+			body:       `{"type": "Create", "actor": "https://example.com/users/bob", "object": {"type": "Note", "content": "<p>hello</p>"}}`,
+			wantResult: false,
+		},
+		{
+			name:       "boost",
+			body:       `{"type": "Announce", "actor": "https://example.com/users/bob", "object": "https://example.net/notes/spam"}`,
+			wantResult: false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := rule.NewNoteContentMatcher("spam")
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			req, err := http.NewRequest("POST", "/inbox", bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+
+			got, err := m.Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
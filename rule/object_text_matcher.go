@@ -0,0 +1,122 @@
+package rule
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ObjectTextField string
+
+const (
+	OBJECT_TEXT_CONTENT         ObjectTextField = "content"
+	OBJECT_TEXT_SUMMARY         ObjectTextField = "summary"
+	OBJECT_TEXT_NAME            ObjectTextField = "name"
+	OBJECT_TEXT_CONTENT_MAP     ObjectTextField = "contentMap"
+	OBJECT_TEXT_ATTACHMENT_NAME ObjectTextField = "attachment.name"
+	OBJECT_TEXT_POLL_OPTIONS    ObjectTextField = "poll.options"
+)
+
+// AllObjectTextFields are all textual fields of an object.
+var AllObjectTextFields = []ObjectTextField{
+	OBJECT_TEXT_CONTENT,
+	OBJECT_TEXT_SUMMARY,
+	OBJECT_TEXT_NAME,
+	OBJECT_TEXT_CONTENT_MAP,
+	OBJECT_TEXT_ATTACHMENT_NAME,
+	OBJECT_TEXT_POLL_OPTIONS,
+}
+
+type objectTextMatcher struct {
+	pattern StringPattern
+	fields  []objectTextSelector
+}
+
+// objectTextSelector selects a field, and a language for contentMap.
+type objectTextSelector struct {
+	field    ObjectTextField
+	language string
+}
+
+// NewObjectTextMatcher creates a matcher testing the fields of the object.
+// contentMap can be restricted to a language as "contentMap.ja".
+func NewObjectTextMatcher(pattern StringPattern, fields []ObjectTextField) (*objectTextMatcher, error) {
+	if pattern == nil {
+		return nil, fmt.Errorf("nil pattern")
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty fields")
+	}
+	selectors := make([]objectTextSelector, 0, len(fields))
+	for _, field := range fields {
+		selector, err := parseObjectTextSelector(field)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+	return &objectTextMatcher{
+		pattern: pattern,
+		fields:  selectors,
+	}, nil
+}
+
+func parseObjectTextSelector(field ObjectTextField) (objectTextSelector, error) {
+	if language, ok := strings.CutPrefix(string(field), string(OBJECT_TEXT_CONTENT_MAP)+"."); ok {
+		if language == "" || language == "*" {
+			return objectTextSelector{field: OBJECT_TEXT_CONTENT_MAP}, nil
+		}
+		return objectTextSelector{field: OBJECT_TEXT_CONTENT_MAP, language: language}, nil
+	}
+	for _, known := range AllObjectTextFields {
+		if field == known {
+			return objectTextSelector{field: field}, nil
+		}
+	}
+	return objectTextSelector{}, fmt.Errorf("unexpected field: %s", field)
+}
+
+func (m *objectTextMatcher) Test(req *ProxyRequest) (bool, error) {
+	object, err := postedObject(req)
+	if err != nil || object == nil {
+		return false, err
+	}
+
+	for _, selector := range m.fields {
+		for _, text := range selector.texts(object) {
+			// absent fields are not tested
+			if text != "" && m.pattern.Match(text) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (s objectTextSelector) texts(object *ActivityObject) []string {
+	switch s.field {
+	case OBJECT_TEXT_CONTENT:
+		return []string{object.Content}
+	case OBJECT_TEXT_SUMMARY:
+		return []string{object.Summary}
+	case OBJECT_TEXT_NAME:
+		return []string{object.Name}
+	case OBJECT_TEXT_CONTENT_MAP:
+		if s.language != "" {
+			return []string{object.ContentMap[s.language]}
+		}
+		texts := make([]string, 0, len(object.ContentMap))
+		for _, text := range object.ContentMap {
+			texts = append(texts, text)
+		}
+		return texts
+	case OBJECT_TEXT_ATTACHMENT_NAME:
+		texts := make([]string, 0, len(object.Attachment))
+		for _, attachment := range object.Attachment {
+			texts = append(texts, attachment.Name)
+		}
+		return texts
+	case OBJECT_TEXT_POLL_OPTIONS:
+		return object.PollOptions
+	}
+	return nil
+}
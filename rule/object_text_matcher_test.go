@@ -0,0 +1,142 @@
+package rule_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestObjectTextMatcher_Test(t *testing.T) {
+	noteBody := `{
+		"type": "Create",
+		"actor": "https://example.com/users/bob",
+		"object": {
+			"type": "Note",
+			"summary": "spam in cw",
+			"content": "<p>hello</p>",
+			"contentMap": {"en": "<p>hello</p>", "ja": "<p>spam in ja</p>"},
+			"attachment": [{"type": "Document", "mediaType": "image/png", "url": "https://example.com/1.png", "name": "spam in alt"}]
+		}
+	}`
+	questionBody := `{
+		"type": "Update",
+		"actor": "https://example.com/users/bob",
+		"object": {
+			"type": "Question",
+			"name": "spam in name",
+			"content": "<p>which?</p>",
+			"anyOf": [{"type": "Note", "name": "ok"}, {"type": "Note", "name": "spam in option"}]
+		}
+	}`
+
+	cases := []struct {
+		name       string
+		path       string
+		body       string
+		fields     []rule.ObjectTextField
+		wantResult bool
+	}{
+		{
+			name:       "content",
+			body:       noteBody,
+			fields:     []rule.ObjectTextField{rule.OBJECT_TEXT_CONTENT},
+			wantResult: false,
+		},
+		{
+			name:       "summary",
+			body:       noteBody,
+			fields:     []rule.ObjectTextField{rule.OBJECT_TEXT_CONTENT, rule.OBJECT_TEXT_SUMMARY},
+			wantResult: true,
+		},
+		{
+			name:       "all languages of contentMap",
+			body:       noteBody,
+			fields:     []rule.ObjectTextField{rule.OBJECT_TEXT_CONTENT_MAP},
+			wantResult: true,
+		},
+		{
+			name:       "specified language of contentMap",
+			body:       noteBody,
+			fields:     []rule.ObjectTextField{"contentMap.ja"},
+			wantResult: true,
+		},
+		{
+			name:       "other language of contentMap",
+			body:       noteBody,
+			fields:     []rule.ObjectTextField{"contentMap.en"},
+			wantResult: false,
+		},
+		{
+			name:       "attachment name",
+			body:       noteBody,
+			fields:     []rule.ObjectTextField{rule.OBJECT_TEXT_ATTACHMENT_NAME},
+			wantResult: true,
+		},
+		{
+			name:       "name",
+			body:       questionBody,
+			fields:     []rule.ObjectTextField{rule.OBJECT_TEXT_NAME},
+			wantResult: true,
+		},
+		{
+			name:       "poll options",
+			body:       questionBody,
+			fields:     []rule.ObjectTextField{rule.OBJECT_TEXT_POLL_OPTIONS},
+			wantResult: true,
+		},
+		{
+			name:       "all fields",
+			body:       questionBody,
+			fields:     rule.AllObjectTextFields,
+			wantResult: true,
+		},
+		{
+			name:       "request other than inbox",
+			path:       "/api/v1/statuses",
+			body:       noteBody,
+			fields:     rule.AllObjectTextFields,
+			wantResult: false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, err := rule.NewContainsPattern("spam", false)
+			if err != nil {
+				t.Fatalf("create pattern: %v", err)
+			}
+			m, err := rule.NewObjectTextMatcher(pattern, tt.fields)
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			path := tt.path
+			if path == "" {
+				path = "/inbox"
+			}
+			req, err := http.NewRequest("POST", path, bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+
+			got, err := m.Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestNewObjectTextMatcher_UnknownField(t *testing.T) {
+	pattern, err := rule.NewContainsPattern("spam", false)
+	if err != nil {
+		t.Fatalf("create pattern: %v", err)
+	}
+	if _, err := rule.NewObjectTextMatcher(pattern, []rule.ObjectTextField{"unknown"}); err == nil {
+		t.Errorf("expected error, but got nil")
+	}
+}
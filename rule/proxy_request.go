@@ -2,14 +2,32 @@ package rule
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"net/http"
+	"net/url"
+
+	"github.com/paralleltree/mastoshield/httpsig"
+	"github.com/paralleltree/mastoshield/lib"
 )
 
 type ProxyRequest struct {
 	// Do not read the request body directly. Use Body() to read it.
-	Request  *http.Request
-	readBody []byte
+	Request *http.Request
+	// ClientIPResolver resolves the client address. lib.DefaultClientIPResolver is used when nil.
+	ClientIPResolver *lib.ClientIPResolver
+	readBody         []byte
+
+	activity      *Activity
+	activityError error
+
+	links       []*url.URL
+	linksParsed bool
+
+	signature        *httpsig.Signature
+	signatureError   error
+	signatureParsed  bool
+	signatureResults map[*httpsig.Verifier]SignatureStatus
 }
 
 func NewProxyRequest(r *http.Request) *ProxyRequest {
@@ -31,3 +49,93 @@ func (r *ProxyRequest) Body() ([]byte, error) {
 	r.Request.Body = io.NopCloser(bytes.NewBuffer(r.readBody))
 	return r.readBody, nil
 }
+
+func (r *ProxyRequest) ClientIP() (string, error) {
+	resolver := r.ClientIPResolver
+	if resolver == nil {
+		resolver = lib.DefaultClientIPResolver
+	}
+	return resolver.Resolve(r.Request)
+}
+
+// Activity returns the request body parsed as an activity.
+// The body is parsed only once and the result is shared by all matchers.
+func (r *ProxyRequest) Activity() (*Activity, error) {
+	if r.activity != nil || r.activityError != nil {
+		return r.activity, r.activityError
+	}
+	body, err := r.Body()
+	if err != nil {
+		r.activityError = err
+		return nil, err
+	}
+	r.activity, r.activityError = ParseActivity(body)
+	return r.activity, r.activityError
+}
+
+// Links returns the links of the activity object, extracted only once.
+func (r *ProxyRequest) Links() ([]*url.URL, error) {
+	if r.linksParsed {
+		return r.links, nil
+	}
+	activity, err := r.Activity()
+	if err != nil {
+		return nil, err
+	}
+	if activity.Object != nil {
+		r.links = activity.Object.Links()
+	}
+	r.linksParsed = true
+	return r.links, nil
+}
+
+// Signature returns the parsed HTTP signature, or nil when the request is not signed.
+func (r *ProxyRequest) Signature() (*httpsig.Signature, error) {
+	if !r.signatureParsed {
+		r.signature, r.signatureError = httpsig.ParseRequestSignature(r.Request)
+		r.signatureParsed = true
+	}
+	return r.signature, r.signatureError
+}
+
+// SignatureStatus verifies the HTTP signature once per verifier.
+// An error is returned only when the verification could not be completed, e.g. the key could not be fetched.
+func (r *ProxyRequest) SignatureStatus(verifier *httpsig.Verifier) (SignatureStatus, error) {
+	if status, ok := r.signatureResults[verifier]; ok {
+		return status, nil
+	}
+
+	status, err := r.verifySignature(verifier)
+	if err != nil {
+		return "", err
+	}
+	if r.signatureResults == nil {
+		r.signatureResults = map[*httpsig.Verifier]SignatureStatus{}
+	}
+	r.signatureResults[verifier] = status
+	return status, nil
+}
+
+func (r *ProxyRequest) verifySignature(verifier *httpsig.Verifier) (SignatureStatus, error) {
+	sig, err := r.Signature()
+	if err != nil {
+		return SIGNATURE_INVALID, nil
+	}
+	if sig == nil {
+		return SIGNATURE_MISSING, nil
+	}
+	body, err := r.Body()
+	if err != nil {
+		return "", err
+	}
+	if err := verifier.Verify(r.Request.Context(), r.Request, sig, body); err != nil {
+		if errors.Is(err, httpsig.ErrVerificationAborted) {
+			return "", err
+		}
+		if errors.Is(err, httpsig.ErrKeyUnavailable) {
+			return SIGNATURE_UNVERIFIABLE, nil
+		}
+		return SIGNATURE_INVALID, nil
+	}
+	return SIGNATURE_VALID, nil
+}
@@ -0,0 +1,42 @@
+package rule_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestProxyRequest_Activity(t *testing.T) {
+	body := `{"type": "Create", "actor": "https://example.com/users/bob", "object": {"type": "Note", "content": "hello"}}`
+	req, err := http.NewRequest("POST", "/inbox", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	proxyRequest := rule.NewProxyRequest(req)
+
+	first, err := proxyRequest.Activity()
+	if err != nil {
+		t.Fatalf("parse activity: %v", err)
+	}
+	second, err := proxyRequest.Activity()
+	if err != nil {
+		t.Fatalf("parse activity: %v", err)
+	}
+	if first != second {
+		t.Errorf("activity is parsed more than once")
+	}
+	if first.Type != "Create" || first.Actor != "https://example.com/users/bob" || first.Object.Content != "hello" {
+		t.Errorf("unexpected activity: %+v", first)
+	}
+
+	forwardedBody, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if string(forwardedBody) != body {
+		t.Errorf("unexpected forwarded body: want %s, but got %s", body, forwardedBody)
+	}
+}
@@ -0,0 +1,197 @@
+package rule
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitKey is the attribute of requests to limit.
+// actor, actor_host and key_id are taken from the request as sent, and are not verified by the limiter.
+type RateLimitKey string
+
+const (
+	RATE_LIMIT_KEY_REMOTE_IP  RateLimitKey = "remote_ip"
+	RATE_LIMIT_KEY_ACTOR      RateLimitKey = "actor"
+	RATE_LIMIT_KEY_ACTOR_HOST RateLimitKey = "actor_host"
+	RATE_LIMIT_KEY_KEY_ID     RateLimitKey = "key_id"
+)
+
+type RateLimitConfig struct {
+	Key RateLimitKey
+	// Rate tokens are refilled every Per.
+	Rate int
+	Per  time.Duration
+	// Burst is the capacity of each bucket.
+	Burst int
+	// MaxKeys bounds the number of tracked keys. The least recently used key is evicted first.
+	MaxKeys int
+}
+
+// RateLimiter is a token bucket rate limiter keyed by an attribute of requests.
+type RateLimiter struct {
+	conf       RateLimitConfig
+	refillRate float64 // tokens per second
+	now        func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	lru     *list.List
+}
+
+type tokenBucket struct {
+	key       string
+	tokens    float64
+	updatedAt time.Time
+}
+
+// NewRateLimiter creates a rate limiter. now is used as the clock, and time.Now is used when nil.
+func NewRateLimiter(conf RateLimitConfig, now func() time.Time) (*RateLimiter, error) {
+	switch conf.Key {
+	case RATE_LIMIT_KEY_REMOTE_IP, RATE_LIMIT_KEY_ACTOR, RATE_LIMIT_KEY_ACTOR_HOST, RATE_LIMIT_KEY_KEY_ID:
+	default:
+		return nil, fmt.Errorf("unexpected rate limit key: %s", conf.Key)
+	}
+	if conf.Rate <= 0 {
+		return nil, fmt.Errorf("invalid rate: %d", conf.Rate)
+	}
+	if conf.Per <= 0 {
+		return nil, fmt.Errorf("invalid period: %v", conf.Per)
+	}
+	if conf.Burst <= 0 {
+		return nil, fmt.Errorf("invalid burst: %d", conf.Burst)
+	}
+	if conf.MaxKeys <= 0 {
+		return nil, fmt.Errorf("invalid max keys: %d", conf.MaxKeys)
+	}
+	if now == nil {
+		now = time.Now
+	}
+	return &RateLimiter{
+		conf:       conf,
+		refillRate: float64(conf.Rate) / conf.Per.Seconds(),
+		now:        now,
+		buckets:    map[string]*list.Element{},
+		lru:        list.New(),
+	}, nil
+}
+
+// Take consumes a token for the request.
+// It returns false and the duration to wait when the bucket is empty.
+// Requests without the key are not limited.
+func (l *RateLimiter) Take(req *ProxyRequest) (bool, time.Duration, error) {
+	key, ok, err := l.resolveKey(req)
+	if err != nil {
+		return false, 0, fmt.Errorf("resolve rate limit key: %w", err)
+	}
+	if !ok {
+		return true, 0, nil
+	}
+	allowed, retryAfter := l.take(key)
+	return allowed, retryAfter, nil
+}
+
+// CarryOver copies the bucket states of prev, so that limits continue across rule reloads.
+// Nothing is copied when the key differs, and tokens over the burst are discarded.
+func (l *RateLimiter) CarryOver(prev *RateLimiter) {
+	if prev == nil || prev == l || prev.conf.Key != l.conf.Key {
+		return
+	}
+	buckets := []tokenBucket{}
+	prev.mu.Lock()
+	for elem := prev.lru.Front(); elem != nil && len(buckets) < l.conf.MaxKeys; elem = elem.Next() {
+		buckets = append(buckets, *elem.Value.(*tokenBucket))
+	}
+	prev.mu.Unlock()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buckets = map[string]*list.Element{}
+	l.lru = list.New()
+	for i := range buckets {
+		bucket := buckets[i]
+		bucket.tokens = math.Min(float64(l.conf.Burst), bucket.tokens)
+		l.buckets[bucket.key] = l.lru.PushBack(&bucket)
+	}
+}
+
+func (l *RateLimiter) take(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	bucket := l.bucket(key, now)
+	elapsed := now.Sub(bucket.updatedAt).Seconds()
+	if elapsed > 0 {
+		bucket.tokens = math.Min(float64(l.conf.Burst), bucket.tokens+elapsed*l.refillRate)
+		bucket.updatedAt = now
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens -= 1
+		return true, 0
+	}
+	wait := (1 - bucket.tokens) / l.refillRate
+	return false, time.Duration(math.Ceil(wait * float64(time.Second)))
+}
+
+func (l *RateLimiter) bucket(key string, now time.Time) *tokenBucket {
+	if elem, ok := l.buckets[key]; ok {
+		l.lru.MoveToFront(elem)
+		return elem.Value.(*tokenBucket)
+	}
+
+	for l.lru.Len() >= l.conf.MaxKeys {
+		oldest := l.lru.Back()
+		l.lru.Remove(oldest)
+		delete(l.buckets, oldest.Value.(*tokenBucket).key)
+	}
+	bucket := &tokenBucket{
+		key:       key,
+		tokens:    float64(l.conf.Burst),
+		updatedAt: now,
+	}
+	l.buckets[key] = l.lru.PushFront(bucket)
+	return bucket
+}
+
+func (l *RateLimiter) resolveKey(req *ProxyRequest) (string, bool, error) {
+	switch l.conf.Key {
+	case RATE_LIMIT_KEY_REMOTE_IP:
+		ip, err := req.ClientIP()
+		if err != nil {
+			return "", false, err
+		}
+		return ip, true, nil
+	case RATE_LIMIT_KEY_ACTOR, RATE_LIMIT_KEY_ACTOR_HOST:
+		if !strings.HasSuffix(req.Request.URL.Path, "/inbox") {
+			return "", false, nil
+		}
+		activity, err := req.Activity()
+		if err != nil {
+			return "", false, err
+		}
+		if activity.Actor == "" {
+			return "", false, nil
+		}
+		if l.conf.Key == RATE_LIMIT_KEY_ACTOR {
+			return activity.Actor, true, nil
+		}
+		u, err := url.Parse(activity.Actor)
+		if err != nil || u.Hostname() == "" {
+			return "", false, nil
+		}
+		return strings.ToLower(u.Hostname()), true, nil
+	case RATE_LIMIT_KEY_KEY_ID:
+		sig, err := req.Signature()
+		if err != nil || sig == nil {
+			return "", false, nil
+		}
+		return sig.KeyID, true, nil
+	}
+	return "", false, fmt.Errorf("unexpected rate limit key: %s", l.conf.Key)
+}
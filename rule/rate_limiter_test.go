@@ -0,0 +1,266 @@
+package rule_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestRateLimiter_Take(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter, err := rule.NewRateLimiter(rule.RateLimitConfig{
+		Key:     rule.RATE_LIMIT_KEY_REMOTE_IP,
+		Rate:    1,
+		Per:     10 * time.Second,
+		Burst:   2,
+		MaxKeys: 10,
+	}, clock.Now)
+	if err != nil {
+		t.Fatalf("create rate limiter: %v", err)
+	}
+
+	cases := []struct {
+		name           string
+		advance        time.Duration
+		remoteAddr     string
+		wantAllowed    bool
+		wantRetryAfter time.Duration
+	}{
+		{name: "first request", remoteAddr: "192.0.2.1:10000", wantAllowed: true},
+		{name: "burst", remoteAddr: "192.0.2.1:10000", wantAllowed: true},
+		{name: "exceeded", remoteAddr: "192.0.2.1:10000", wantAllowed: false, wantRetryAfter: 10 * time.Second},
+		{name: "another key", remoteAddr: "192.0.2.2:10000", wantAllowed: true},
+		{name: "partially refilled", advance: 4 * time.Second, remoteAddr: "192.0.2.1:10000", wantAllowed: false, wantRetryAfter: 6 * time.Second},
+		{name: "refilled", advance: 6 * time.Second, remoteAddr: "192.0.2.1:10000", wantAllowed: true},
+		{name: "exceeded after refill", remoteAddr: "192.0.2.1:10000", wantAllowed: false, wantRetryAfter: 10 * time.Second},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Advance(tt.advance)
+			req, err := http.NewRequest("GET", "/", nil)
+			if err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+			req.RemoteAddr = tt.remoteAddr
+
+			allowed, retryAfter, err := limiter.Take(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantAllowed != allowed {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantAllowed, allowed)
+			}
+			if tt.wantRetryAfter != retryAfter {
+				t.Errorf("unexpected retry after: want %v, but got %v", tt.wantRetryAfter, retryAfter)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Keys(t *testing.T) {
+	body := func(actor string) string {
+		return fmt.Sprintf(`{"type": "Create", "actor": "%s", "object": {"type": "Note", "content": "hello"}}`, actor)
+	}
+
+	cases := []struct {
+		name        string
+		key         rule.RateLimitKey
+		first       string
+		second      string
+		path        string
+		wantAllowed bool
+	}{
+		{
+			name:        "same actor",
+			key:         rule.RATE_LIMIT_KEY_ACTOR,
+			path:        "/inbox",
+			first:       "https://example.com/users/alice",
+			second:      "https://example.com/users/alice",
+			wantAllowed: false,
+		},
+		{
+			name:        "different actor",
+			key:         rule.RATE_LIMIT_KEY_ACTOR,
+			path:        "/inbox",
+			first:       "https://example.com/users/alice",
+			second:      "https://example.com/users/bob",
+			wantAllowed: true,
+		},
+		{
+			name:        "same actor host",
+			key:         rule.RATE_LIMIT_KEY_ACTOR_HOST,
+			path:        "/inbox",
+			first:       "https://example.com/users/alice",
+			second:      "https://EXAMPLE.com/users/bob",
+			wantAllowed: false,
+		},
+		{
+			name:        "different actor host",
+			key:         rule.RATE_LIMIT_KEY_ACTOR_HOST,
+			path:        "/inbox",
+			first:       "https://example.com/users/alice",
+			second:      "https://example.org/users/alice",
+			wantAllowed: true,
+		},
+		{
+			name:        "not limited without actor",
+			key:         rule.RATE_LIMIT_KEY_ACTOR,
+			path:        "/users/alice",
+			first:       "https://example.com/users/alice",
+			second:      "https://example.com/users/alice",
+			wantAllowed: true,
+		},
+		{
+			name:        "not limited without signature",
+			key:         rule.RATE_LIMIT_KEY_KEY_ID,
+			path:        "/inbox",
+			first:       "https://example.com/users/alice",
+			second:      "https://example.com/users/alice",
+			wantAllowed: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, err := rule.NewRateLimiter(rule.RateLimitConfig{
+				Key:     tt.key,
+				Rate:    1,
+				Per:     time.Minute,
+				Burst:   1,
+				MaxKeys: 10,
+			}, (&fakeClock{}).Now)
+			if err != nil {
+				t.Fatalf("create rate limiter: %v", err)
+			}
+
+			var allowed bool
+			for _, actor := range []string{tt.first, tt.second} {
+				req, err := http.NewRequest("POST", tt.path, bytes.NewBufferString(body(actor)))
+				if err != nil {
+					t.Fatalf("create request: %v", err)
+				}
+				allowed, _, err = limiter.Take(rule.NewProxyRequest(req))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if tt.wantAllowed != allowed {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantAllowed, allowed)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_EvictsLeastRecentlyUsedKey(t *testing.T) {
+	limiter, err := rule.NewRateLimiter(rule.RateLimitConfig{
+		Key:     rule.RATE_LIMIT_KEY_REMOTE_IP,
+		Rate:    1,
+		Per:     time.Minute,
+		Burst:   1,
+		MaxKeys: 2,
+	}, (&fakeClock{}).Now)
+	if err != nil {
+		t.Fatalf("create rate limiter: %v", err)
+	}
+
+	take := func(remoteAddr string) bool {
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+		req.RemoteAddr = remoteAddr
+		allowed, _, err := limiter.Take(rule.NewProxyRequest(req))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return allowed
+	}
+
+	take("192.0.2.1:10000")
+	take("192.0.2.2:10000")
+	// 192.0.2.1 becomes the most recently used key
+	if take("192.0.2.1:10000") {
+		t.Errorf("exceeded request is allowed")
+	}
+	// evicts 192.0.2.2
+	take("192.0.2.3:10000")
+
+	if take("192.0.2.1:10000") {
+		t.Errorf("recently used key is evicted")
+	}
+	if !take("192.0.2.2:10000") {
+		t.Errorf("least recently used key is not evicted")
+	}
+}
+
+func TestRateLimiter_CarryOver(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	newLimiter := func(key rule.RateLimitKey, burst int) *rule.RateLimiter {
+		limiter, err := rule.NewRateLimiter(rule.RateLimitConfig{
+			Key:     key,
+			Rate:    1,
+			Per:     time.Minute,
+			Burst:   burst,
+			MaxKeys: 10,
+		}, clock.Now)
+		if err != nil {
+			t.Fatalf("create rate limiter: %v", err)
+		}
+		return limiter
+	}
+	take := func(limiter *rule.RateLimiter) bool {
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+		req.RemoteAddr = "192.0.2.1:10000"
+		allowed, _, err := limiter.Take(rule.NewProxyRequest(req))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return allowed
+	}
+
+	cases := []struct {
+		name        string
+		key         rule.RateLimitKey
+		burst       int
+		wantAllowed bool
+	}{
+		{name: "same config", key: rule.RATE_LIMIT_KEY_REMOTE_IP, burst: 1, wantAllowed: false},
+		{name: "burst is raised", key: rule.RATE_LIMIT_KEY_REMOTE_IP, burst: 2, wantAllowed: false},
+		{name: "key is changed", key: rule.RATE_LIMIT_KEY_ACTOR, burst: 1, wantAllowed: true},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := newLimiter(rule.RATE_LIMIT_KEY_REMOTE_IP, 1)
+			if !take(prev) {
+				t.Fatalf("first request is limited")
+			}
+
+			next := newLimiter(tt.key, tt.burst)
+			next.CarryOver(prev)
+			if got := take(next); tt.wantAllowed != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantAllowed, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package rule
+
+import (
+	"fmt"
+	"strings"
+)
+
+type remoteCountryMatcher struct {
+	db        *GeoIPDatabase
+	countries map[string]struct{}
+}
+
+func NewRemoteCountryMatcher(db *GeoIPDatabase, countries []string) (*remoteCountryMatcher, error) {
+	if db == nil {
+		return nil, fmt.Errorf("nil database")
+	}
+	if len(countries) == 0 {
+		return nil, fmt.Errorf("empty countries")
+	}
+	countrySet := map[string]struct{}{}
+	for _, country := range countries {
+		if len(country) != 2 {
+			return nil, fmt.Errorf("invalid country code: %s", country)
+		}
+		countrySet[strings.ToUpper(country)] = struct{}{}
+	}
+	return &remoteCountryMatcher{
+		db:        db,
+		countries: countrySet,
+	}, nil
+}
+
+func (m *remoteCountryMatcher) Test(req *ProxyRequest) (bool, error) {
+	remoteIP, err := parseClientIP(req)
+	if err != nil {
+		return false, err
+	}
+	country, err := m.db.Country(remoteIP)
+	if err != nil {
+		return false, err
+	}
+	_, ok := m.countries[country]
+	return ok, nil
+}
+
+type remoteASNMatcher struct {
+	db   *GeoIPDatabase
+	asns map[uint]struct{}
+}
+
+func NewRemoteASNMatcher(db *GeoIPDatabase, asns []uint) (*remoteASNMatcher, error) {
+	if db == nil {
+		return nil, fmt.Errorf("nil database")
+	}
+	if len(asns) == 0 {
+		return nil, fmt.Errorf("empty asns")
+	}
+	asnSet := map[uint]struct{}{}
+	for _, asn := range asns {
+		if asn == 0 {
+			return nil, fmt.Errorf("invalid asn: %d", asn)
+		}
+		asnSet[asn] = struct{}{}
+	}
+	return &remoteASNMatcher{
+		db:   db,
+		asns: asnSet,
+	}, nil
+}
+
+func (m *remoteASNMatcher) Test(req *ProxyRequest) (bool, error) {
+	remoteIP, err := parseClientIP(req)
+	if err != nil {
+		return false, err
+	}
+	asn, err := m.db.ASN(remoteIP)
+	if err != nil {
+		return false, err
+	}
+	_, ok := m.asns[asn]
+	return ok, nil
+}
@@ -0,0 +1,93 @@
+package rule_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestRemoteCountryMatcher_Test(t *testing.T) {
+	db, err := rule.LoadGeoIPDatabase(writeGeoIPFixture(t, "GeoLite2-Country", map[string]mmdbtype.Map{
+		"192.0.2.0/24":    countryRecord("JP"),
+		"198.51.100.0/24": countryRecord("US"),
+	}))
+	if err != nil {
+		t.Fatalf("load database: %v", err)
+	}
+	m, err := rule.NewRemoteCountryMatcher(db, []string{"jp", "DE"})
+	if err != nil {
+		t.Fatalf("create matcher: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		wantResult bool
+	}{
+		{name: "address in specified country", remoteAddr: "192.0.2.1:10000", wantResult: true},
+		{name: "address in other country", remoteAddr: "198.51.100.1:10000", wantResult: false},
+		{name: "unknown address", remoteAddr: "203.0.113.1:10000", wantResult: false},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/", nil)
+			if err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+			req.RemoteAddr = tt.remoteAddr
+
+			got, err := m.Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestRemoteASNMatcher_Test(t *testing.T) {
+	db, err := rule.LoadGeoIPDatabase(writeGeoIPFixture(t, "GeoLite2-ASN", map[string]mmdbtype.Map{
+		"192.0.2.0/24":    asnRecord(64500, "Example Hosting"),
+		"198.51.100.0/24": asnRecord(64501, "Example ISP"),
+	}))
+	if err != nil {
+		t.Fatalf("load database: %v", err)
+	}
+	m, err := rule.NewRemoteASNMatcher(db, []uint{64500, 64502})
+	if err != nil {
+		t.Fatalf("create matcher: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		wantResult bool
+	}{
+		{name: "address in specified asn", remoteAddr: "192.0.2.1:10000", wantResult: true},
+		{name: "address in other asn", remoteAddr: "198.51.100.1:10000", wantResult: false},
+		{name: "unknown address", remoteAddr: "203.0.113.1:10000", wantResult: false},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/", nil)
+			if err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+			req.RemoteAddr = tt.remoteAddr
+
+			got, err := m.Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
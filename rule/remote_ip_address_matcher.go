@@ -3,32 +3,50 @@ package rule
 import (
 	"fmt"
 	"net"
-
-	"github.com/paralleltree/mastoshield/lib"
+	"net/netip"
 )
 
 type remoteIPAddressMatcher struct {
-	targetRange *net.IPNet
+	ranges *IPRangeSet
 }
 
 func NewRemoteIPAddressMatcher(cidr string) (*remoteIPAddressMatcher, error) {
-	_, targetRange, err := net.ParseCIDR(cidr)
+	ranges, err := NewIPRangeSet([]string{cidr})
 	if err != nil {
-		return nil, fmt.Errorf("parse cidr: %w", err)
+		return nil, err
+	}
+	return NewRemoteIPRangeMatcher(ranges)
+}
+
+func NewRemoteIPRangeMatcher(ranges *IPRangeSet) (*remoteIPAddressMatcher, error) {
+	if ranges == nil {
+		return nil, fmt.Errorf("nil ip range set")
 	}
 	return &remoteIPAddressMatcher{
-		targetRange: targetRange,
+		ranges: ranges,
 	}, nil
 }
 
 func (m *remoteIPAddressMatcher) Test(req *ProxyRequest) (bool, error) {
-	remoteAddr, err := lib.ResolveClientIP(req.Request)
+	remoteIP, err := parseClientIP(req)
+	if err != nil {
+		return false, err
+	}
+	addr, ok := netip.AddrFromSlice(remoteIP)
+	if !ok {
+		return false, fmt.Errorf("cannot parse IP address: %s", remoteIP)
+	}
+	return m.ranges.Contains(addr), nil
+}
+
+func parseClientIP(req *ProxyRequest) (net.IP, error) {
+	remoteAddr, err := req.ClientIP()
 	if err != nil {
-		return false, fmt.Errorf("resolve client addr: %w", err)
+		return nil, fmt.Errorf("resolve client addr: %w", err)
 	}
 	remoteIP := net.ParseIP(remoteAddr)
 	if remoteIP == nil {
-		return false, fmt.Errorf("cannot parse IP address: %s", remoteAddr)
+		return nil, fmt.Errorf("cannot parse IP address: %s", remoteAddr)
 	}
-	return m.targetRange.Contains(remoteIP), nil
+	return remoteIP, nil
 }
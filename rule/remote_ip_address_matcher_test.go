@@ -85,6 +85,8 @@ func TestRemoteIPAddressMatcher_ForXForwardedFor(t *testing.T) {
 			if err != nil {
 				t.Fatalf("create request: %v", err)
 			}
+			// forwarding headers are trusted only when the request comes from a trusted proxy
+			req.RemoteAddr = "127.0.0.1:30000"
 			req.Header.Set("X-Forwarded-For", fmt.Sprintf("%s, %s", tt.remoteIP, "127.0.0.1"))
 
 			gotResult, err := m.Test(rule.NewProxyRequest(req))
@@ -97,3 +99,43 @@ func TestRemoteIPAddressMatcher_ForXForwardedFor(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoteIPRangeMatcher_Test(t *testing.T) {
+	ranges, err := rule.NewIPRangeSet([]string{"192.0.2.0/24", "198.51.100.10", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("create ip range set: %v", err)
+	}
+	m, err := rule.NewRemoteIPRangeMatcher(ranges)
+	if err != nil {
+		t.Fatalf("create matcher: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		wantResult bool
+	}{
+		{name: "ipv4 range", remoteAddr: "192.0.2.1:30000", wantResult: true},
+		{name: "ipv4 address", remoteAddr: "198.51.100.10:30000", wantResult: true},
+		{name: "ipv6 range", remoteAddr: "[2001:db8::1]:30000", wantResult: true},
+		{name: "not in ranges", remoteAddr: "[2001:db9::1]:30000", wantResult: false},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/", nil)
+			if err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+			req.RemoteAddr = tt.remoteAddr
+
+			gotResult, err := m.Test(rule.NewProxyRequest(req))
+			if err != nil {
+				t.Fatalf("test: %v", err)
+			}
+			if tt.wantResult != gotResult {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, gotResult)
+			}
+		})
+	}
+}
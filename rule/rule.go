@@ -1,10 +1,16 @@
 package rule
 
+import "net/http"
+
 type ActionType int
 
 const (
 	ACTION_ALLOW ActionType = 0
 	ACTION_DENY  ActionType = 1
+	// ACTION_LOG only records the match and continues evaluating subsequent rulesets.
+	ACTION_LOG ActionType = 2
+	// ACTION_RATE_LIMIT denies requests exceeding the rate limit, and continues evaluating otherwise.
+	ACTION_RATE_LIMIT ActionType = 3
 )
 
 type RuleMatcher interface {
@@ -12,6 +18,18 @@ type RuleMatcher interface {
 }
 
 type RuleSet struct {
+	Name     string
 	Action   ActionType
 	Matchers []RuleMatcher
+	// Response overrides the default response of the deny action when specified.
+	Response *DenyResponse
+	// RateLimiter is used by the rate_limit action.
+	RateLimiter *RateLimiter
+}
+
+type DenyResponse struct {
+	// StatusCode is the status of the response. The default deny status is used when zero.
+	StatusCode int
+	Header     http.Header
+	Body       []byte
 }
@@ -0,0 +1,118 @@
+package rule
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/paralleltree/mastoshield/httpsig"
+)
+
+type SignatureStatus string
+
+const (
+	SIGNATURE_MISSING SignatureStatus = "missing"
+	SIGNATURE_INVALID SignatureStatus = "invalid"
+	SIGNATURE_VALID   SignatureStatus = "valid"
+	// SIGNATURE_UNVERIFIABLE is the status of signatures whose key could not be fetched.
+	// It is also matched by SIGNATURE_INVALID.
+	SIGNATURE_UNVERIFIABLE SignatureStatus = "unverifiable"
+)
+
+func ParseSignatureStatus(s string) (SignatureStatus, error) {
+	switch status := SignatureStatus(strings.ToLower(s)); status {
+	case SIGNATURE_MISSING, SIGNATURE_INVALID, SIGNATURE_VALID, SIGNATURE_UNVERIFIABLE:
+		return status, nil
+	}
+	return "", fmt.Errorf("unexpected signature status: %s", s)
+}
+
+func isInboxDelivery(req *ProxyRequest) bool {
+	return req.Request.Method == "POST" && strings.HasSuffix(req.Request.URL.Path, "/inbox")
+}
+
+type signatureStatusMatcher struct {
+	verifier *httpsig.Verifier
+	statuses []SignatureStatus
+}
+
+func NewSignatureStatusMatcher(verifier *httpsig.Verifier, statuses []SignatureStatus) (*signatureStatusMatcher, error) {
+	if verifier == nil {
+		return nil, fmt.Errorf("nil verifier")
+	}
+	if len(statuses) == 0 {
+		return nil, fmt.Errorf("empty statuses")
+	}
+	return &signatureStatusMatcher{
+		verifier: verifier,
+		statuses: statuses,
+	}, nil
+}
+
+func (m *signatureStatusMatcher) Test(req *ProxyRequest) (bool, error) {
+	if !isInboxDelivery(req) {
+		return false, nil
+	}
+
+	status, err := req.SignatureStatus(m.verifier)
+	if err != nil {
+		return false, fmt.Errorf("verify signature: %w", err)
+	}
+	for _, s := range m.statuses {
+		if s == status || (s == SIGNATURE_INVALID && status == SIGNATURE_UNVERIFIABLE) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type signatureKeyDomainMatcher struct {
+	pattern StringPattern
+}
+
+func NewSignatureKeyDomainMatcher(pattern StringPattern) (*signatureKeyDomainMatcher, error) {
+	if pattern == nil {
+		return nil, fmt.Errorf("nil pattern")
+	}
+	return &signatureKeyDomainMatcher{
+		pattern: pattern,
+	}, nil
+}
+
+func (m *signatureKeyDomainMatcher) Test(req *ProxyRequest) (bool, error) {
+	if !isInboxDelivery(req) {
+		return false, nil
+	}
+
+	sig, err := req.Signature()
+	if err != nil || sig == nil {
+		return false, nil
+	}
+	return m.pattern.Match(sig.KeyHost()), nil
+}
+
+type signatureActorMismatchMatcher struct{}
+
+func NewSignatureActorMismatchMatcher() (*signatureActorMismatchMatcher, error) {
+	return &signatureActorMismatchMatcher{}, nil
+}
+
+func (m *signatureActorMismatchMatcher) Test(req *ProxyRequest) (bool, error) {
+	if !isInboxDelivery(req) {
+		return false, nil
+	}
+
+	sig, err := req.Signature()
+	if err != nil || sig == nil {
+		return false, nil
+	}
+	activity, err := req.Activity()
+	if err != nil {
+		return false, fmt.Errorf("parse activity: %w", err)
+	}
+	actor, err := url.Parse(activity.Actor)
+	if err != nil || actor.Hostname() == "" {
+		return false, nil
+	}
+	return !strings.EqualFold(actor.Hostname(), sig.KeyHost()), nil
+}
@@ -0,0 +1,243 @@
+package rule_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/paralleltree/mastoshield/httpsig"
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestSignatureMatchers(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	const keyID = "https://example.com/users/alice#main-key"
+	verifier, err := httpsig.NewVerifier(httpsig.StaticKeyFetcher{keyID: key.Public()}, time.Hour, signedAt)
+	if err != nil {
+		t.Fatalf("create verifier: %v", err)
+	}
+	keyDomainPattern, err := rule.NewEqualsPattern("example.com", false)
+	if err != nil {
+		t.Fatalf("create pattern: %v", err)
+	}
+
+	newRequest := func(actor string, sign bool, tamper bool) *http.Request {
+		body := []byte(`{"type": "Create", "actor": "` + actor + `", "object": {"type": "Note", "content": "hello"}}`)
+		req, err := http.NewRequest("POST", "https://example.net/inbox", bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+		req.Header.Set("Date", "Sun, 18 Feb 2024 04:52:27 GMT")
+		if sign {
+			if err := signRequest(req, keyID, key, []string{"(request-target)", "host", "date", "digest"}, body); err != nil {
+				t.Fatalf("sign request: %v", err)
+			}
+		}
+		if tamper {
+			req.Header.Set("Date", "Sun, 18 Feb 2024 05:00:00 GMT")
+		}
+		return req
+	}
+
+	cases := []struct {
+		name           string
+		req            *http.Request
+		wantStatus     rule.SignatureStatus
+		wantKeyDomain  bool
+		wantMismatched bool
+	}{
+		{
+			name:          "valid signature",
+			req:           newRequest("https://example.com/users/alice", true, false),
+			wantStatus:    rule.SIGNATURE_VALID,
+			wantKeyDomain: true,
+		},
+		{
+			name:       "missing signature",
+			req:        newRequest("https://example.com/users/alice", false, false),
+			wantStatus: rule.SIGNATURE_MISSING,
+		},
+		{
+			name:          "invalid signature",
+			req:           newRequest("https://example.com/users/alice", true, true),
+			wantStatus:    rule.SIGNATURE_INVALID,
+			wantKeyDomain: true,
+		},
+		{
+			name:           "actor is spoofed",
+			req:            newRequest("https://example.org/users/mallory", true, false),
+			wantStatus:     rule.SIGNATURE_VALID,
+			wantKeyDomain:  true,
+			wantMismatched: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			proxyRequest := rule.NewProxyRequest(tt.req)
+
+			for _, status := range []rule.SignatureStatus{rule.SIGNATURE_MISSING, rule.SIGNATURE_INVALID, rule.SIGNATURE_VALID} {
+				m, err := rule.NewSignatureStatusMatcher(verifier, []rule.SignatureStatus{status})
+				if err != nil {
+					t.Fatalf("create matcher: %v", err)
+				}
+				gotResult, err := m.Test(proxyRequest)
+				if err != nil {
+					t.Fatalf("test: %v", err)
+				}
+				if wantResult := status == tt.wantStatus; wantResult != gotResult {
+					t.Errorf("unexpected result for status %s: want %v, but got %v", status, wantResult, gotResult)
+				}
+			}
+
+			keyDomainMatcher, err := rule.NewSignatureKeyDomainMatcher(keyDomainPattern)
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			gotKeyDomain, err := keyDomainMatcher.Test(proxyRequest)
+			if err != nil {
+				t.Fatalf("test: %v", err)
+			}
+			if tt.wantKeyDomain != gotKeyDomain {
+				t.Errorf("unexpected key domain result: want %v, but got %v", tt.wantKeyDomain, gotKeyDomain)
+			}
+
+			mismatchMatcher, err := rule.NewSignatureActorMismatchMatcher()
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			gotMismatched, err := mismatchMatcher.Test(proxyRequest)
+			if err != nil {
+				t.Fatalf("test: %v", err)
+			}
+			if tt.wantMismatched != gotMismatched {
+				t.Errorf("unexpected mismatch result: want %v, but got %v", tt.wantMismatched, gotMismatched)
+			}
+		})
+	}
+}
+
+func TestSignatureStatusMatcher_NotInbox(t *testing.T) {
+	verifier, err := httpsig.NewVerifier(httpsig.StaticKeyFetcher{}, time.Hour, nil)
+	if err != nil {
+		t.Fatalf("create verifier: %v", err)
+	}
+	m, err := rule.NewSignatureStatusMatcher(verifier, []rule.SignatureStatus{rule.SIGNATURE_MISSING})
+	if err != nil {
+		t.Fatalf("create matcher: %v", err)
+	}
+	req, err := http.NewRequest("GET", "/api/v1/timelines/home", nil)
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	gotResult, err := m.Test(rule.NewProxyRequest(req))
+	if err != nil {
+		t.Fatalf("test: %v", err)
+	}
+	if gotResult {
+		t.Errorf("unsigned request except inbox deliveries is matched")
+	}
+}
+
+func signedAt() time.Time {
+	return time.Date(2024, 2, 18, 4, 52, 27, 0, time.UTC)
+}
+
+type unavailableKeyFetcher struct{}
+
+func (unavailableKeyFetcher) FetchKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestSignatureStatusMatcher_KeyUnavailable(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	verifier, err := httpsig.NewVerifier(unavailableKeyFetcher{}, time.Hour, signedAt)
+	if err != nil {
+		t.Fatalf("create verifier: %v", err)
+	}
+	body := []byte(`{"type": "Create", "actor": "https://example.com/users/alice", "object": {"type": "Note", "content": "hello"}}`)
+	req, err := http.NewRequest("POST", "https://example.net/inbox", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	req.Header.Set("Date", "Sun, 18 Feb 2024 04:52:27 GMT")
+	if err := signRequest(req, "https://example.com/users/alice#main-key", key, []string{"(request-target)", "host", "date", "digest"}, body); err != nil {
+		t.Fatalf("sign request: %v", err)
+	}
+	proxyRequest := rule.NewProxyRequest(req)
+
+	cases := []struct {
+		status     rule.SignatureStatus
+		wantResult bool
+	}{
+		{status: rule.SIGNATURE_UNVERIFIABLE, wantResult: true},
+		{status: rule.SIGNATURE_INVALID, wantResult: true},
+		{status: rule.SIGNATURE_MISSING, wantResult: false},
+		{status: rule.SIGNATURE_VALID, wantResult: false},
+	}
+	for _, tt := range cases {
+		t.Run(string(tt.status), func(t *testing.T) {
+			m, err := rule.NewSignatureStatusMatcher(verifier, []rule.SignatureStatus{tt.status})
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			gotResult, err := m.Test(proxyRequest)
+			if err != nil {
+				t.Fatalf("test: %v", err)
+			}
+			if tt.wantResult != gotResult {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, gotResult)
+			}
+		})
+	}
+}
+
+type blockingKeyFetcher struct{}
+
+func (blockingKeyFetcher) FetchKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestSignatureStatusMatcher_Aborted(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	verifier, err := httpsig.NewVerifier(blockingKeyFetcher{}, time.Hour, signedAt)
+	if err != nil {
+		t.Fatalf("create verifier: %v", err)
+	}
+	body := []byte(`{"type": "Create", "actor": "https://example.com/users/alice", "object": {"type": "Note", "content": "hello"}}`)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://example.net/inbox", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	req.Header.Set("Date", "Sun, 18 Feb 2024 04:52:27 GMT")
+	if err := signRequest(req, "https://example.com/users/alice#main-key", key, []string{"(request-target)", "host", "date", "digest"}, body); err != nil {
+		t.Fatalf("sign request: %v", err)
+	}
+
+	m, err := rule.NewSignatureStatusMatcher(verifier, []rule.SignatureStatus{rule.SIGNATURE_INVALID})
+	if err != nil {
+		t.Fatalf("create matcher: %v", err)
+	}
+	// an aborted verification is neither valid nor invalid
+	if _, err := m.Test(rule.NewProxyRequest(req)); !errors.Is(err, httpsig.ErrVerificationAborted) {
+		t.Errorf("unexpected error: want %v, but got %v", httpsig.ErrVerificationAborted, err)
+	}
+}
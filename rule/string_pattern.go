@@ -0,0 +1,78 @@
+package rule
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type StringPattern interface {
+	Match(text string) bool
+}
+
+type literalPattern struct {
+	pattern    string
+	ignoreCase bool
+	match      func(text, pattern string) bool
+}
+
+func newLiteralPattern(pattern string, ignoreCase bool, match func(text, pattern string) bool) (*literalPattern, error) {
+	if len(pattern) == 0 {
+		return nil, fmt.Errorf("empty pattern text")
+	}
+	if ignoreCase {
+		pattern = strings.ToLower(pattern)
+	}
+	return &literalPattern{
+		pattern:    pattern,
+		ignoreCase: ignoreCase,
+		match:      match,
+	}, nil
+}
+
+func NewContainsPattern(pattern string, ignoreCase bool) (*literalPattern, error) {
+	return newLiteralPattern(pattern, ignoreCase, strings.Contains)
+}
+
+func NewPrefixPattern(pattern string, ignoreCase bool) (*literalPattern, error) {
+	return newLiteralPattern(pattern, ignoreCase, strings.HasPrefix)
+}
+
+func NewSuffixPattern(pattern string, ignoreCase bool) (*literalPattern, error) {
+	return newLiteralPattern(pattern, ignoreCase, strings.HasSuffix)
+}
+
+func NewEqualsPattern(pattern string, ignoreCase bool) (*literalPattern, error) {
+	return newLiteralPattern(pattern, ignoreCase, func(text, pattern string) bool { return text == pattern })
+}
+
+func (p *literalPattern) Match(text string) bool {
+	if p.ignoreCase {
+		text = strings.ToLower(text)
+	}
+	return p.match(text, p.pattern)
+}
+
+type regexpPattern struct {
+	re *regexp.Regexp
+}
+
+func NewRegexpPattern(expr string, ignoreCase bool) (*regexpPattern, error) {
+	if len(expr) == 0 {
+		return nil, fmt.Errorf("empty regular expression")
+	}
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("compile regular expression: %w", err)
+	}
+	return &regexpPattern{
+		re: re,
+	}, nil
+}
+
+func (p *regexpPattern) Match(text string) bool {
+	return p.re.MatchString(text)
+}
@@ -0,0 +1,95 @@
+package rule_test
+
+import (
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestStringPattern(t *testing.T) {
+	cases := []struct {
+		name       string
+		newPattern func() (rule.StringPattern, error)
+		text       string
+		wantResult bool
+	}{
+		{
+			name:       "contains",
+			newPattern: func() (rule.StringPattern, error) { return rule.NewContainsPattern("spam", false) },
+			text:       "this is spam text",
+			wantResult: true,
+		},
+		{
+			name:       "contains is case sensitive by default",
+			newPattern: func() (rule.StringPattern, error) { return rule.NewContainsPattern("spam", false) },
+			text:       "this is SPAM text",
+			wantResult: false,
+		},
+		{
+			name:       "contains ignoring case",
+			newPattern: func() (rule.StringPattern, error) { return rule.NewContainsPattern("Spam", true) },
+			text:       "this is SPAM text",
+			wantResult: true,
+		},
+		{
+			name:       "starts with",
+			newPattern: func() (rule.StringPattern, error) { return rule.NewPrefixPattern("https://example.com/", false) },
+			text:       "https://example.com/users/bob",
+			wantResult: true,
+		},
+		{
+			name:       "ends with",
+			newPattern: func() (rule.StringPattern, error) { return rule.NewSuffixPattern(".example.com", false) },
+			text:       "https://example.com/users/bob",
+			wantResult: false,
+		},
+		{
+			name:       "ends with ignoring case",
+			newPattern: func() (rule.StringPattern, error) { return rule.NewSuffixPattern("/BOB", true) },
+			text:       "https://example.com/users/bob",
+			wantResult: true,
+		},
+		{
+			name:       "equals",
+			newPattern: func() (rule.StringPattern, error) { return rule.NewEqualsPattern("spam", false) },
+			text:       "spam!",
+			wantResult: false,
+		},
+		{
+			name:       "equals ignoring case",
+			newPattern: func() (rule.StringPattern, error) { return rule.NewEqualsPattern("spam", true) },
+			text:       "SPAM",
+			wantResult: true,
+		},
+		{
+			name:       "regular expression",
+			newPattern: func() (rule.StringPattern, error) { return rule.NewRegexpPattern(`s+p+a+m+`, false) },
+			text:       "ssppaamm",
+			wantResult: true,
+		},
+		{
+			name:       "regular expression ignoring case",
+			newPattern: func() (rule.StringPattern, error) { return rule.NewRegexpPattern(`^s+p+a+m+$`, true) },
+			text:       "SsPAm",
+			wantResult: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := tt.newPattern()
+			if err != nil {
+				t.Fatalf("create pattern: %v", err)
+			}
+			if gotResult := p.Match(tt.text); tt.wantResult != gotResult {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, gotResult)
+			}
+		})
+	}
+}
+
+func TestRegexpPattern_InvalidExpression(t *testing.T) {
+	if _, err := rule.NewRegexpPattern(`(unclosed`, false); err == nil {
+		t.Errorf("expected error, but got nil")
+	}
+}
@@ -0,0 +1,47 @@
+package rule
+
+import "fmt"
+
+// tagCountMatcher matches when the number of tags of the type satisfies the condition.
+type tagCountMatcher struct {
+	tagType   string
+	condition CountCondition
+}
+
+func NewMentionCountMatcher(condition CountCondition) (*tagCountMatcher, error) {
+	return newTagCountMatcher("Mention", condition)
+}
+
+func NewHashtagCountMatcher(condition CountCondition) (*tagCountMatcher, error) {
+	return newTagCountMatcher("Hashtag", condition)
+}
+
+func NewCustomEmojiCountMatcher(condition CountCondition) (*tagCountMatcher, error) {
+	return newTagCountMatcher("Emoji", condition)
+}
+
+func newTagCountMatcher(tagType string, condition CountCondition) (*tagCountMatcher, error) {
+	if err := condition.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid condition: %w", err)
+	}
+	return &tagCountMatcher{
+		tagType:   tagType,
+		condition: condition,
+	}, nil
+}
+
+func (m *tagCountMatcher) Test(req *ProxyRequest) (bool, error) {
+	object, err := postedObject(req)
+	if err != nil || object == nil {
+		return false, err
+	}
+
+	count := 0
+	for _, tag := range object.Tag {
+		if tag.Type == m.tagType {
+			count += 1
+		}
+	}
+
+	return m.condition.Match(count), nil
+}
@@ -0,0 +1,85 @@
+package rule_test
+
+import (
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func testTags() []map[string]any {
+	return []map[string]any{
+		{"type": "Mention", "href": "https://example.com/users/alice", "name": "@alice@example.com"},
+		{"type": "Hashtag", "href": "https://example.com/tags/free", "name": "#Free"},
+		{"type": "Hashtag", "href": "https://example.com/tags/followers", "name": "#followers"},
+		{"type": "Hashtag", "href": "https://example.com/tags/giveaway", "name": "#giveaway"},
+		{"type": "Emoji", "name": ":money:", "icon": map[string]any{"type": "Image", "url": "https://example.com/emoji/money.png"}},
+	}
+}
+
+func TestTagCountMatcher_Test(t *testing.T) {
+	cases := []struct {
+		name       string
+		newMatcher func(condition rule.CountCondition) (rule.RuleMatcher, error)
+		condition  rule.CountCondition
+		wantResult bool
+	}{
+		{
+			name:       "more hashtags than threshold",
+			newMatcher: func(c rule.CountCondition) (rule.RuleMatcher, error) { return rule.NewHashtagCountMatcher(c) },
+			condition:  rule.CountCondition{GreaterThan: intPtr(2)},
+			wantResult: true,
+		},
+		{
+			name:       "hashtags equal to threshold",
+			newMatcher: func(c rule.CountCondition) (rule.RuleMatcher, error) { return rule.NewHashtagCountMatcher(c) },
+			condition:  rule.CountCondition{GreaterThan: intPtr(3)},
+			wantResult: false,
+		},
+		{
+			name:       "fewer hashtags than threshold",
+			newMatcher: func(c rule.CountCondition) (rule.RuleMatcher, error) { return rule.NewHashtagCountMatcher(c) },
+			condition:  rule.CountCondition{LessThan: intPtr(4)},
+			wantResult: true,
+		},
+		{
+			name:       "hashtags out of range",
+			newMatcher: func(c rule.CountCondition) (rule.RuleMatcher, error) { return rule.NewHashtagCountMatcher(c) },
+			condition:  rule.CountCondition{GreaterThan: intPtr(0), LessThan: intPtr(3)},
+			wantResult: false,
+		},
+		{
+			name:       "more custom emojis than threshold",
+			newMatcher: func(c rule.CountCondition) (rule.RuleMatcher, error) { return rule.NewCustomEmojiCountMatcher(c) },
+			condition:  rule.CountCondition{GreaterThan: intPtr(0)},
+			wantResult: true,
+		},
+		{
+			name:       "custom emojis equal to threshold",
+			newMatcher: func(c rule.CountCondition) (rule.RuleMatcher, error) { return rule.NewCustomEmojiCountMatcher(c) },
+			condition:  rule.CountCondition{GreaterThan: intPtr(1)},
+			wantResult: false,
+		},
+		{
+			name:       "mentions equal to count",
+			newMatcher: func(c rule.CountCondition) (rule.RuleMatcher, error) { return rule.NewMentionCountMatcher(c) },
+			condition:  rule.CountCondition{Equal: intPtr(1)},
+			wantResult: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := tt.newMatcher(tt.condition)
+			if err != nil {
+				t.Fatalf("create matcher: %v", err)
+			}
+			got, err := m.Test(newObjectRequest(t, "/inbox", "Create", map[string]any{"type": "Note", "content": "", "tag": testTags()}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantResult != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.wantResult, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package rule
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+type NormalizeStep string
+
+const (
+	// NORMALIZE_HTML strips tags and decodes character references.
+	NORMALIZE_HTML NormalizeStep = "html"
+	// NORMALIZE_NFKC applies Unicode NFKC, e.g. folds fullwidth letters.
+	NORMALIZE_NFKC NormalizeStep = "nfkc"
+	// NORMALIZE_INVISIBLE removes invisible characters such as zero-width spaces.
+	NORMALIZE_INVISIBLE NormalizeStep = "invisible"
+	// NORMALIZE_CONFUSABLES replaces letters looking like ASCII letters with them.
+	NORMALIZE_CONFUSABLES NormalizeStep = "confusables"
+	// NORMALIZE_CASEFOLD applies Unicode case folding.
+	NORMALIZE_CASEFOLD NormalizeStep = "casefold"
+)
+
+// normalizeSteps is the order in which the steps are applied.
+var normalizeSteps = []NormalizeStep{NORMALIZE_HTML, NORMALIZE_NFKC, NORMALIZE_INVISIBLE, NORMALIZE_CONFUSABLES, NORMALIZE_CASEFOLD}
+
+// DefaultNormalizeSteps are all steps except for confusables folding, which may change legitimate text in other scripts.
+var DefaultNormalizeSteps = []NormalizeStep{NORMALIZE_HTML, NORMALIZE_NFKC, NORMALIZE_INVISIBLE, NORMALIZE_CASEFOLD}
+
+// blankInvisibles are characters rendered as blank, but not classified as format characters.
+var blankInvisibles = map[rune]struct{}{
+	'\u034f': {}, // combining grapheme joiner
+	'\u115f': {}, // hangul choseong filler
+	'\u1160': {}, // hangul jungseong filler
+	'\u2800': {}, // braille pattern blank
+	'\u3164': {}, // hangul filler
+	'\uffa0': {}, // halfwidth hangul filler
+}
+
+// blockElements separate words when tags are stripped.
+var blockElements = map[atom.Atom]struct{}{
+	atom.Br: {}, atom.P: {}, atom.Div: {}, atom.Li: {}, atom.Blockquote: {}, atom.Pre: {},
+	atom.H1: {}, atom.H2: {}, atom.H3: {}, atom.H4: {}, atom.H5: {}, atom.H6: {},
+}
+
+type TextNormalizer struct {
+	steps map[NormalizeStep]struct{}
+}
+
+func NewTextNormalizer(steps []NormalizeStep) (*TextNormalizer, error) {
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("empty normalize steps")
+	}
+	stepSet := map[NormalizeStep]struct{}{}
+	for _, step := range steps {
+		known := false
+		for _, s := range normalizeSteps {
+			known = known || s == step
+		}
+		if !known {
+			return nil, fmt.Errorf("unexpected normalize step: %s", step)
+		}
+		stepSet[step] = struct{}{}
+	}
+	return &TextNormalizer{
+		steps: stepSet,
+	}, nil
+}
+
+// Normalize applies the steps in the fixed order regardless of the given order.
+func (n *TextNormalizer) Normalize(text string) string {
+	return n.normalize(text, false)
+}
+
+// NormalizePattern normalizes a plain text pattern, skipping the html step.
+func (n *TextNormalizer) NormalizePattern(pattern string) string {
+	return n.normalize(pattern, true)
+}
+
+func (n *TextNormalizer) normalize(text string, skipHTML bool) string {
+	for _, step := range normalizeSteps {
+		if _, ok := n.steps[step]; !ok {
+			continue
+		}
+		switch step {
+		case NORMALIZE_HTML:
+			if !skipHTML {
+				text = htmlToText(text)
+			}
+		case NORMALIZE_NFKC:
+			text = norm.NFKC.String(text)
+		case NORMALIZE_INVISIBLE:
+			text = strings.Map(func(r rune) rune {
+				if _, ok := blankInvisibles[r]; ok || unicode.Is(unicode.Cf, r) || unicode.Is(unicode.Variation_Selector, r) {
+					return -1
+				}
+				return r
+			}, text)
+		case NORMALIZE_CONFUSABLES:
+			text = strings.Map(func(r rune) rune {
+				if latin, ok := latinConfusables[r]; ok {
+					return latin
+				}
+				return r
+			}, text)
+		case NORMALIZE_CASEFOLD:
+			text = cases.Fold().String(text)
+		}
+	}
+	return text
+}
+
+// htmlToText extracts text from HTML, separating block elements with newlines.
+// Surrounding spaces are trimmed so that the text can be tested by equals patterns.
+func htmlToText(content string) string {
+	b := strings.Builder{}
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// io.EOF, or the text extracted so far on a broken document
+			return strings.TrimSpace(b.String())
+		case html.TextToken:
+			b.Write(tokenizer.Text())
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			if _, ok := blockElements[atom.Lookup(name)]; ok {
+				b.WriteByte('\n')
+			}
+		}
+	}
+}
+
+type normalizedPattern struct {
+	pattern    StringPattern
+	normalizer *TextNormalizer
+}
+
+// NewNormalizedPattern creates a pattern which normalizes text before matching.
+func NewNormalizedPattern(pattern StringPattern, normalizer *TextNormalizer) (*normalizedPattern, error) {
+	if pattern == nil {
+		return nil, fmt.Errorf("nil pattern")
+	}
+	if normalizer == nil {
+		return nil, fmt.Errorf("nil normalizer")
+	}
+	return &normalizedPattern{
+		pattern:    pattern,
+		normalizer: normalizer,
+	}, nil
+}
+
+func (p *normalizedPattern) Match(text string) bool {
+	return p.pattern.Match(p.normalizer.Normalize(text))
+}
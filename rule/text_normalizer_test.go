@@ -0,0 +1,126 @@
+package rule_test
+
+import (
+	"testing"
+
+	"github.com/paralleltree/mastoshield/rule"
+)
+
+func TestTextNormalizer_Normalize(t *testing.T) {
+	cases := []struct {
+		name  string
+		steps []rule.NormalizeStep
+		text  string
+		want  string
+	}{
+		{
+			name:  "split words and entities",
+			steps: []rule.NormalizeStep{rule.NORMALIZE_HTML},
+			text:  `<p>sp<span>a</span>m &amp; sc&#97;m</p><p>next</p>line<br>break`,
+			want:  "spam & scam\n\nnext\nline\nbreak",
+		},
+		{
+			name:  "fullwidth letters",
+			steps: []rule.NormalizeStep{rule.NORMALIZE_NFKC},
+			text:  "ｓｐａｍ ＳＰＡＭ ｽﾊﾟﾑ",
+			want:  "spam SPAM スパム",
+		},
+		{
+			name:  "invisible characters",
+			steps: []rule.NormalizeStep{rule.NORMALIZE_INVISIBLE},
+			text:  "s\u200bp\u200da\u2060m\ufeff \u3164x\u00ady",
+			want:  "spam xy",
+		},
+		{
+			name:  "confusables",
+			steps: []rule.NormalizeStep{rule.NORMALIZE_CONFUSABLES},
+			text:  "ѕрам Ѕсам привет",
+			want:  "spam Scam пpиbet",
+		},
+		{
+			name:  "case folding",
+			steps: []rule.NormalizeStep{rule.NORMALIZE_CASEFOLD},
+			text:  "SPAM Straße",
+			want:  "spam strasse",
+		},
+		{
+			name:  "steps are applied in fixed order",
+			steps: []rule.NormalizeStep{rule.NORMALIZE_CASEFOLD, rule.NORMALIZE_INVISIBLE, rule.NORMALIZE_NFKC, rule.NORMALIZE_HTML},
+			text:  "<p>Ｓ&#x200b;<b>P</b>am</p>",
+			want:  "spam",
+		},
+		{
+			name:  "default steps",
+			steps: rule.DefaultNormalizeSteps,
+			text:  "<p>ＦＲＥＥ f&#8203;ollowers</p>",
+			want:  "free followers",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := rule.NewTextNormalizer(tt.steps)
+			if err != nil {
+				t.Fatalf("create normalizer: %v", err)
+			}
+			if got := n.Normalize(tt.text); tt.want != got {
+				t.Errorf("unexpected result: want %q, but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTextNormalizer_NormalizePattern(t *testing.T) {
+	n, err := rule.NewTextNormalizer(rule.DefaultNormalizeSteps)
+	if err != nil {
+		t.Fatalf("create normalizer: %v", err)
+	}
+	if got := n.NormalizePattern("<b>ＳＰＡＭ</b>"); got != "<b>spam</b>" {
+		t.Errorf("unexpected result: want %q, but got %q", "<b>spam</b>", got)
+	}
+}
+
+func TestNewTextNormalizer_UnknownStep(t *testing.T) {
+	if _, err := rule.NewTextNormalizer([]rule.NormalizeStep{"unknown"}); err == nil {
+		t.Errorf("expected error, but got nil")
+	}
+	if _, err := rule.NewTextNormalizer(nil); err == nil {
+		t.Errorf("expected error, but got nil")
+	}
+}
+
+func TestNormalizedPattern_Match(t *testing.T) {
+	n, err := rule.NewTextNormalizer([]rule.NormalizeStep{rule.NORMALIZE_HTML, rule.NORMALIZE_NFKC, rule.NORMALIZE_INVISIBLE, rule.NORMALIZE_CONFUSABLES, rule.NORMALIZE_CASEFOLD})
+	if err != nil {
+		t.Fatalf("create normalizer: %v", err)
+	}
+	contains, err := rule.NewContainsPattern("free followers", false)
+	if err != nil {
+		t.Fatalf("create pattern: %v", err)
+	}
+	p, err := rule.NewNormalizedPattern(contains, n)
+	if err != nil {
+		t.Fatalf("create pattern: %v", err)
+	}
+
+	cases := []struct {
+		text string
+		want bool
+	}{
+		{text: "<p>Get FREE followers now</p>", want: true},
+		{text: "<p>Get f<span>r</span>ee followers now</p>", want: true},
+		{text: "<p>Get ｆｒｅｅ ｆｏｌｌｏｗｅｒｓ now</p>", want: true},
+		{text: "<p>Get fr\u200bee fo\u200cllowers now</p>", want: true},
+		{text: "<p>Get frее fоllоwеrs now</p>", want: true},
+		{text: "<p>Get free&nbsp;followers now</p>", want: true},
+		{text: "<p>Get followers for free</p>", want: false},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := p.Match(tt.text); tt.want != got {
+				t.Errorf("unexpected result: want %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
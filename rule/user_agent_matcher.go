@@ -1,17 +1,22 @@
 package rule
 
-import (
-	"fmt"
-	"strings"
-)
+import "fmt"
 
 type userAgentMatcher struct {
-	pattern string
+	pattern StringPattern
 }
 
 func NewUserAgentMatcher(pattern string) (*userAgentMatcher, error) {
-	if len(pattern) == 0 {
-		return nil, fmt.Errorf("empty pattern text")
+	containsPattern, err := NewContainsPattern(pattern, false)
+	if err != nil {
+		return nil, err
+	}
+	return NewUserAgentPatternMatcher(containsPattern)
+}
+
+func NewUserAgentPatternMatcher(pattern StringPattern) (*userAgentMatcher, error) {
+	if pattern == nil {
+		return nil, fmt.Errorf("nil pattern")
 	}
 	return &userAgentMatcher{
 		pattern: pattern,
@@ -19,5 +24,5 @@ func NewUserAgentMatcher(pattern string) (*userAgentMatcher, error) {
 }
 
 func (m *userAgentMatcher) Test(req *ProxyRequest) (bool, error) {
-	return strings.Contains(req.Request.Header.Get("User-Agent"), m.pattern), nil
+	return m.pattern.Match(req.Request.Header.Get("User-Agent")), nil
 }